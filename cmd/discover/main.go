@@ -0,0 +1,69 @@
+// Command discover finds the feeds advertised by a website and prints them
+// as feeds.FeedConfig entries ready to paste into feeds.Feeds.
+//
+//	go run ./cmd/discover https://www.example.com [more URLs...]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"numerosnumerosnumeros_agg/tools"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	timeout := flag.Duration("timeout", 2*time.Minute, "overall timeout")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: discover [-timeout 2m] <website URL>...")
+		os.Exit(2)
+	}
+
+	_ = godotenv.Load()
+
+	email := os.Getenv("MAIN_EMAIL")
+	if email == "" {
+		fmt.Fprintln(os.Stderr, "MAIN_EMAIL not set")
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	userAgents := tools.NewAgents(email)
+	fetcher := tools.NewFetcher(tools.FetcherOptions{})
+
+	failed := false
+	for _, pageURL := range flag.Args() {
+		found, err := tools.DiscoverFeeds(ctx, fetcher, userAgents, pageURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", pageURL, err)
+			failed = true
+			continue
+		}
+
+		for _, f := range found {
+			if f.Err != nil {
+				fmt.Printf("\t// %s (%s) failed to parse: %v\n", f.Config.URL, f.Type, f.Err)
+				continue
+			}
+
+			fmt.Printf("\t// %s, %d items\n", f.Title, f.ItemCount)
+			fmt.Printf("\t{\n")
+			fmt.Printf("\t\tURL:     %q,\n", f.Config.URL)
+			fmt.Printf("\t\tHeader:  %q,\n", f.Config.Header)
+			fmt.Printf("\t\tProfile: %q,\n", f.Config.Profile)
+			fmt.Printf("\t},\n")
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
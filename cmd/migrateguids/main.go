@@ -0,0 +1,178 @@
+// Command migrateguids carries a feed's published records over to a new
+// GUID strategy. Switching away from "feed" needs no migration, since the
+// feed keys stay among a post's AltGUIDs; this is for the other switches,
+// e.g. "link" to "title", or to rewrite old records under the new primary
+// key before deleting the old ones. It fetches the feed, keys every item
+// under both strategies and copies the published record of an old key to
+// each new key. Under "feed" the old keys include the bare GUIDs (and raw
+// links) records were written with before keys were versioned.
+//
+//	go run ./cmd/migrateguids -feed https://search.cnbc.com/... -from feed [-to link] [-delete] [-dry-run]
+//
+// Run it right before (or after) deploying the config change; items that
+// already left the feed are covered by the high-water mark.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"numerosnumerosnumeros_agg/dynamo"
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/tools"
+	"numerosnumerosnumeros_agg/typesPkg"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	feedURL := flag.String("feed", "", "URL of the feed in feeds.Feeds")
+	from := flag.String("from", "feed", "GUID strategy the records were written with")
+	to := flag.String("to", "", "new GUID strategy (default: the feed's configured one)")
+	deleteOld := flag.Bool("delete", false, "delete records under keys the new strategy no longer uses")
+	dryRun := flag.Bool("dry-run", false, "only print what would change")
+	timeout := flag.Duration("timeout", 5*time.Minute, "overall timeout")
+	flag.Parse()
+
+	if *feedURL == "" {
+		fmt.Fprintln(os.Stderr, "usage: migrateguids -feed <URL> [-from feed] [-to link] [-delete] [-dry-run]")
+		os.Exit(2)
+	}
+
+	_ = godotenv.Load()
+
+	email := os.Getenv("MAIN_EMAIL")
+	if email == "" {
+		fmt.Fprintln(os.Stderr, "MAIN_EMAIL not set")
+		os.Exit(1)
+	}
+
+	var fc feeds.FeedConfig
+	found := false
+	for _, f := range feeds.Feeds {
+		if f.URL == *feedURL {
+			fc, found = f, true
+			break
+		}
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "%s is not in feeds.Feeds\n", *feedURL)
+		os.Exit(1)
+	}
+
+	if *to == "" {
+		*to = fc.GUIDStrategy
+	}
+	for _, strategy := range []string{*from, *to} {
+		if _, err := tools.ParseGUIDStrategy(strategy); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	sdkConfig, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to load SDK config: %v\n", err)
+		os.Exit(1)
+	}
+	db := dynamodb.NewFromConfig(sdkConfig)
+
+	userAgents := tools.NewAgents(email)
+	fetcher := tools.NewFetcher(tools.FetcherOptions{})
+
+	parsed, err := tools.ParseRSSFeed(ctx, fetcher, userAgents, fc, typesPkg.FeedValidators{}, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fc.URL, err)
+		os.Exit(1)
+	}
+	posts, err := tools.PreparePosts(parsed.Posts, fc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fc.URL, err)
+		os.Exit(1)
+	}
+
+	copied, failed := 0, false
+	for _, post := range posts {
+		oldKeys := strategyKeys(post, fc, *from)
+		newKeys := strategyKeys(post, fc, *to)
+
+		source := ""
+		for _, key := range oldKeys {
+			pub, err := dynamo.IsArticlePublished(ctx, db, key)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", key, err)
+				failed = true
+				break
+			}
+			if pub {
+				source = key
+				break
+			}
+		}
+		if source == "" {
+			continue // never published, nothing to carry over
+		}
+
+		keep := make(map[string]bool, len(newKeys))
+		for _, key := range newKeys {
+			keep[key] = true
+		}
+
+		for _, key := range newKeys {
+			if key == source {
+				continue
+			}
+			fmt.Printf("%s -> %s\n", source, key)
+			if *dryRun {
+				continue
+			}
+			if _, err := dynamo.CopyPublished(ctx, db, source, key); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+				continue
+			}
+			copied++
+		}
+
+		if !*deleteOld {
+			continue
+		}
+		for _, key := range oldKeys {
+			if keep[key] {
+				continue
+			}
+			fmt.Printf("delete %s\n", key)
+			if *dryRun {
+				continue
+			}
+			if err := dynamo.DeletePublished(ctx, db, key); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+	}
+
+	fmt.Printf("%d items in feed, %d records copied\n", len(posts), copied)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// strategyKeys is every key the post is looked up under with strategy,
+// primary first.
+func strategyKeys(post typesPkg.MainStruct, fc feeds.FeedConfig, strategy string) []string {
+	fc.GUIDStrategy = strategy
+	keyed, err := tools.ApplyGUIDStrategy([]typesPkg.MainStruct{post}, fc)
+	if err != nil || len(keyed) == 0 {
+		return nil // strategies were validated up front
+	}
+	return append([]string{keyed[0].GUID}, keyed[0].AltGUIDs...)
+}
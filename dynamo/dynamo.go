@@ -13,6 +13,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+const tableName = "numerosnumerosnumeros_agg_table"
+
 type PublishedArticleRecord struct {
 	GUID      string `dynamodbav:"guid"`      // Main table PK
 	Timestamp int64  `dynamodbav:"timestamp"` // Main table SK
@@ -21,7 +23,7 @@ type PublishedArticleRecord struct {
 
 func IsArticlePublished(ctx context.Context, db *dynamodb.Client, guid string) (bool, error) {
 	result, err := db.Query(ctx, &dynamodb.QueryInput{
-		TableName:              aws.String("numerosnumerosnumeros_agg_table"),
+		TableName:              aws.String(tableName),
 		KeyConditionExpression: aws.String("guid = :guid"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":guid": &types.AttributeValueMemberS{Value: guid},
@@ -47,28 +49,44 @@ func BatchMarkPublished(
 	now := time.Now()
 	ttl := now.AddDate(1, 0, 0).Unix()
 
+	// a batch may not contain the same key twice
+	seen := make(map[string]bool, len(articles))
+
 	for _, art := range articles {
-		rec := PublishedArticleRecord{
-			GUID:      art.GUID,
-			Timestamp: now.Unix(),
-			TTL:       ttl,
-		}
-		item, err := attributevalue.MarshalMap(rec)
-		if err != nil {
-			return fmt.Errorf("marshal record: %w", err)
+		for _, guid := range append([]string{art.GUID}, art.AltGUIDs...) {
+			if guid == "" || seen[guid] {
+				continue
+			}
+			seen[guid] = true
+
+			rec := PublishedArticleRecord{
+				GUID:      guid,
+				Timestamp: now.Unix(),
+				TTL:       ttl,
+			}
+			item, err := attributevalue.MarshalMap(rec)
+			if err != nil {
+				return fmt.Errorf("marshal record: %w", err)
+			}
+			writes = append(writes, types.WriteRequest{
+				PutRequest: &types.PutRequest{Item: item},
+			})
 		}
-		writes = append(writes, types.WriteRequest{
-			PutRequest: &types.PutRequest{Item: item},
-		})
 	}
 
+	return batchPut(ctx, db, writes)
+}
+
+// batchPut writes in batches of 25 (the BatchWriteItem maximum) and retries
+// unprocessed items once.
+func batchPut(ctx context.Context, db *dynamodb.Client, writes []types.WriteRequest) error {
 	// max 25 per batch
 	for i := 0; i < len(writes); i += 25 {
 		end := min(i+25, len(writes))
 		batch := writes[i:end]
 		input := &dynamodb.BatchWriteItemInput{
 			RequestItems: map[string][]types.WriteRequest{
-				"numerosnumerosnumeros_agg_table": batch,
+				tableName: batch,
 			},
 		}
 
@@ -78,10 +96,10 @@ func BatchMarkPublished(
 		}
 
 		// retry unprocessed items if any
-		if un := resp.UnprocessedItems["numerosnumerosnumeros_agg_table"]; len(un) > 0 {
+		if un := resp.UnprocessedItems[tableName]; len(un) > 0 {
 			retryInput := &dynamodb.BatchWriteItemInput{
 				RequestItems: map[string][]types.WriteRequest{
-					"numerosnumerosnumeros_agg_table": un,
+					tableName: un,
 				},
 			}
 			if _, err := db.BatchWriteItem(ctx, retryInput); err != nil {
@@ -92,3 +110,68 @@ func BatchMarkPublished(
 
 	return nil
 }
+
+// CopyPublished writes a published record for newGUID with the timestamp and
+// TTL of oldGUID's record. It reports false when oldGUID isn't published.
+func CopyPublished(ctx context.Context, db *dynamodb.Client, oldGUID, newGUID string) (bool, error) {
+	result, err := db.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("guid = :guid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":guid": &types.AttributeValueMemberS{Value: oldGUID},
+		},
+		Limit: aws.Int32(1),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to query DynamoDB: %w", err)
+	}
+	if len(result.Items) == 0 {
+		return false, nil
+	}
+
+	var rec PublishedArticleRecord
+	if err := attributevalue.UnmarshalMap(result.Items[0], &rec); err != nil {
+		return false, fmt.Errorf("unmarshal record: %w", err)
+	}
+
+	rec.GUID = newGUID
+	item, err := attributevalue.MarshalMap(rec)
+	if err != nil {
+		return false, fmt.Errorf("marshal record: %w", err)
+	}
+	if _, err := db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item:      item,
+	}); err != nil {
+		return false, fmt.Errorf("failed to write %q: %w", newGUID, err)
+	}
+
+	return true, nil
+}
+
+// DeletePublished removes every published record of guid.
+func DeletePublished(ctx context.Context, db *dynamodb.Client, guid string) error {
+	result, err := db.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("guid = :guid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":guid": &types.AttributeValueMemberS{Value: guid},
+		},
+		ProjectionExpression:     aws.String("guid, #ts"),
+		ExpressionAttributeNames: map[string]string{"#ts": "timestamp"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query DynamoDB: %w", err)
+	}
+
+	for _, key := range result.Items {
+		if _, err := db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(tableName),
+			Key:       key,
+		}); err != nil {
+			return fmt.Errorf("failed to delete %q: %w", guid, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package dynamo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"numerosnumerosnumeros_agg/typesPkg"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Feed state lives in the same table as the published-article records,
+// under a reserved GUID prefix and a fixed sort key.
+const feedStatePrefix = "feedstate:"
+
+type FeedStateRecord struct {
+	GUID         string `dynamodbav:"guid"`      // feedStatePrefix + feed URL
+	Timestamp    int64  `dynamodbav:"timestamp"` // always 0
+	ETag         string `dynamodbav:"etag,omitempty"`
+	LastModified string `dynamodbav:"last_modified,omitempty"`
+
+	LastSuccess         int64  `dynamodbav:"last_success,omitempty"`
+	LastAttempt         int64  `dynamodbav:"last_attempt,omitempty"`
+	ConsecutiveFailures int    `dynamodbav:"consecutive_failures,omitempty"`
+	LastError           string `dynamodbav:"last_error,omitempty"`
+	LastItemCount       int    `dynamodbav:"last_item_count,omitempty"`
+	OpenUntil           int64  `dynamodbav:"open_until,omitempty"`
+
+	Cookies string `dynamodbav:"cookies,omitempty"` // JSON encoded []typesPkg.StoredCookie
+
+	HighWater int64 `dynamodbav:"high_water,omitempty"`
+
+	TTL int64 `dynamodbav:"ttl"`
+}
+
+func feedStateKey(feedURL string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"guid":      &types.AttributeValueMemberS{Value: feedStatePrefix + feedURL},
+		"timestamp": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func timeOrZero(secs int64) time.Time {
+	if secs == 0 {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0)
+}
+
+func GetFeedState(ctx context.Context, db *dynamodb.Client, feedURL string) (typesPkg.FeedState, error) {
+	result, err := db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key:       feedStateKey(feedURL),
+	})
+	if err != nil {
+		return typesPkg.FeedState{}, fmt.Errorf("failed to get feed state: %w", err)
+	}
+	if result.Item == nil {
+		return typesPkg.FeedState{}, nil
+	}
+
+	var rec FeedStateRecord
+	if err := attributevalue.UnmarshalMap(result.Item, &rec); err != nil {
+		return typesPkg.FeedState{}, fmt.Errorf("unmarshal feed state: %w", err)
+	}
+
+	var cookies []typesPkg.StoredCookie
+	if rec.Cookies != "" {
+		if err := json.Unmarshal([]byte(rec.Cookies), &cookies); err != nil {
+			return typesPkg.FeedState{}, fmt.Errorf("unmarshal feed cookies: %w", err)
+		}
+	}
+
+	return typesPkg.FeedState{
+		Cookies:   cookies,
+		HighWater: timeOrZero(rec.HighWater),
+		Validators: typesPkg.FeedValidators{
+			ETag:         rec.ETag,
+			LastModified: rec.LastModified,
+		},
+		Health: typesPkg.FeedHealth{
+			LastSuccess:         timeOrZero(rec.LastSuccess),
+			LastAttempt:         timeOrZero(rec.LastAttempt),
+			ConsecutiveFailures: rec.ConsecutiveFailures,
+			LastError:           rec.LastError,
+			LastItemCount:       rec.LastItemCount,
+			OpenUntil:           timeOrZero(rec.OpenUntil),
+		},
+	}, nil
+}
+
+// updateFeedState only SETs the given attributes so that the different parts
+// of the per-feed state can be saved independently of each other.
+func updateFeedState(ctx context.Context, db *dynamodb.Client, feedURL string, values map[string]types.AttributeValue) error {
+	ttl := time.Now().AddDate(0, 3, 0).Unix()
+	values["ttl"] = &types.AttributeValueMemberN{Value: fmt.Sprint(ttl)}
+
+	attrs := make([]string, 0, len(values))
+	for attr := range values {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+
+	names := make(map[string]string, len(values))
+	exprValues := make(map[string]types.AttributeValue, len(values))
+	sets := make([]string, 0, len(values))
+
+	// Placeholders are numbered since attribute names contain underscores
+	for i, attr := range attrs {
+		name, value := fmt.Sprintf("#a%d", i), fmt.Sprintf(":v%d", i)
+		names[name] = attr
+		exprValues[value] = values[attr]
+		sets = append(sets, name+" = "+value)
+	}
+
+	_, err := db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(tableName),
+		Key:                       feedStateKey(feedURL),
+		UpdateExpression:          aws.String("SET " + strings.Join(sets, ", ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: exprValues,
+	})
+	return err
+}
+
+func SaveFeedValidators(ctx context.Context, db *dynamodb.Client, feedURL string, v typesPkg.FeedValidators) error {
+	err := updateFeedState(ctx, db, feedURL, map[string]types.AttributeValue{
+		"etag":          &types.AttributeValueMemberS{Value: v.ETag},
+		"last_modified": &types.AttributeValueMemberS{Value: v.LastModified},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save feed validators: %w", err)
+	}
+
+	return nil
+}
+
+func SaveFeedHealth(ctx context.Context, db *dynamodb.Client, feedURL string, h typesPkg.FeedHealth) error {
+	err := updateFeedState(ctx, db, feedURL, map[string]types.AttributeValue{
+		"last_success":         &types.AttributeValueMemberN{Value: fmt.Sprint(unixOrZero(h.LastSuccess))},
+		"last_attempt":         &types.AttributeValueMemberN{Value: fmt.Sprint(unixOrZero(h.LastAttempt))},
+		"consecutive_failures": &types.AttributeValueMemberN{Value: fmt.Sprint(h.ConsecutiveFailures)},
+		"last_error":           &types.AttributeValueMemberS{Value: h.LastError},
+		"last_item_count":      &types.AttributeValueMemberN{Value: fmt.Sprint(h.LastItemCount)},
+		"open_until":           &types.AttributeValueMemberN{Value: fmt.Sprint(unixOrZero(h.OpenUntil))},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save feed health: %w", err)
+	}
+
+	return nil
+}
+
+func SaveFeedCookies(ctx context.Context, db *dynamodb.Client, feedURL string, cookies []typesPkg.StoredCookie) error {
+	b, err := json.Marshal(cookies)
+	if err != nil {
+		return fmt.Errorf("marshal feed cookies: %w", err)
+	}
+
+	err = updateFeedState(ctx, db, feedURL, map[string]types.AttributeValue{
+		"cookies": &types.AttributeValueMemberS{Value: string(b)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save feed cookies: %w", err)
+	}
+
+	return nil
+}
+
+func SaveFeedHighWater(ctx context.Context, db *dynamodb.Client, feedURL string, highWater time.Time) error {
+	err := updateFeedState(ctx, db, feedURL, map[string]types.AttributeValue{
+		"high_water": &types.AttributeValueMemberN{Value: fmt.Sprint(unixOrZero(highWater))},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save feed high-water mark: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package dynamo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"numerosnumerosnumeros_agg/typesPkg"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Posted stories share one reserved GUID and are keyed by posting time in
+// nanoseconds, so a window is a single range query.
+const storiesGUID = "__stories"
+
+// Story records only need to outlive the longest dedup window
+const storyTTL = 7 * 24 * time.Hour
+
+type StoryRecord struct {
+	GUID           string   `dynamodbav:"guid"`      // storiesGUID
+	Timestamp      int64    `dynamodbav:"timestamp"` // posting time, unix nanoseconds
+	StoryGUID      string   `dynamodbav:"story_guid"`
+	Title          string   `dynamodbav:"title"`
+	Header         string   `dynamodbav:"header"`
+	Link           string   `dynamodbav:"link,omitempty"`
+	Words          []string `dynamodbav:"words,omitempty"`
+	MessageID      int64    `dynamodbav:"message_id,omitempty"`
+	Related        string   `dynamodbav:"related,omitempty"` // JSON encoded []typesPkg.Coverage
+	DiscussionLink string   `dynamodbav:"discussion_link,omitempty"`
+	TTL            int64    `dynamodbav:"ttl"`
+}
+
+func storyKey(key int64) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"guid":      &types.AttributeValueMemberS{Value: storiesGUID},
+		"timestamp": &types.AttributeValueMemberN{Value: fmt.Sprint(key)},
+	}
+}
+
+func marshalRelated(related []typesPkg.Coverage) (string, error) {
+	if len(related) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(related)
+	if err != nil {
+		return "", fmt.Errorf("marshal related coverage: %w", err)
+	}
+	return string(b), nil
+}
+
+func RecentStories(ctx context.Context, db *dynamodb.Client, since time.Time) ([]typesPkg.Story, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("guid = :guid AND #ts >= :since"),
+		ExpressionAttributeNames: map[string]string{
+			"#ts": "timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":guid":  &types.AttributeValueMemberS{Value: storiesGUID},
+			":since": &types.AttributeValueMemberN{Value: fmt.Sprint(since.UnixNano())},
+		},
+	}
+
+	var stories []typesPkg.Story
+	paginator := dynamodb.NewQueryPaginator(db, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query recent stories: %w", err)
+		}
+
+		var recs []StoryRecord
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &recs); err != nil {
+			return nil, fmt.Errorf("unmarshal stories: %w", err)
+		}
+		for _, rec := range recs {
+			var related []typesPkg.Coverage
+			if rec.Related != "" {
+				// A damaged list only costs the related buttons
+				_ = json.Unmarshal([]byte(rec.Related), &related)
+			}
+			stories = append(stories, typesPkg.Story{
+				Key:            rec.Timestamp,
+				GUID:           rec.StoryGUID,
+				Title:          rec.Title,
+				Header:         rec.Header,
+				Link:           rec.Link,
+				Words:          rec.Words,
+				Posted:         time.Unix(0, rec.Timestamp),
+				MessageID:      rec.MessageID,
+				Related:        related,
+				DiscussionLink: rec.DiscussionLink,
+			})
+		}
+	}
+
+	return stories, nil
+}
+
+func SaveStories(ctx context.Context, db *dynamodb.Client, stories []typesPkg.Story) error {
+	writes := make([]types.WriteRequest, 0, len(stories))
+	ttl := time.Now().Add(storyTTL).Unix()
+
+	for i, story := range stories {
+		related, err := marshalRelated(story.Related)
+		if err != nil {
+			return err
+		}
+		rec := StoryRecord{
+			GUID:           storiesGUID,
+			Timestamp:      story.Posted.UnixNano() + int64(i), // keep sort keys unique
+			StoryGUID:      story.GUID,
+			Title:          story.Title,
+			Header:         story.Header,
+			Link:           story.Link,
+			Words:          story.Words,
+			MessageID:      story.MessageID,
+			Related:        related,
+			DiscussionLink: story.DiscussionLink,
+			TTL:            ttl,
+		}
+		item, err := attributevalue.MarshalMap(rec)
+		if err != nil {
+			return fmt.Errorf("marshal story: %w", err)
+		}
+		writes = append(writes, types.WriteRequest{
+			PutRequest: &types.PutRequest{Item: item},
+		})
+	}
+
+	return batchPut(ctx, db, writes)
+}
+
+// SaveStoryRelated replaces the related coverage of an already saved story.
+func SaveStoryRelated(ctx context.Context, db *dynamodb.Client, story typesPkg.Story) error {
+	if story.Key == 0 {
+		return fmt.Errorf("story %q has not been saved", story.GUID)
+	}
+
+	related, err := marshalRelated(story.Related)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(tableName),
+		Key:                 storyKey(story.Key),
+		UpdateExpression:    aws.String("SET related = :related"),
+		ConditionExpression: aws.String("attribute_exists(guid)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":related": &types.AttributeValueMemberS{Value: related},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save related coverage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package feeds
+
+// FetchProfile describes how requests for a feed are dressed up. Profiles
+// are defined once in Profiles and referenced by name from FeedConfig.Profile.
+// Header, cookie, query and token values are expanded with os.ExpandEnv so
+// that secrets can stay in the environment.
+type FetchProfile struct {
+	UserAgent   string            // "bot", "chrome", "reader" or a literal User-Agent string
+	Accept      string            // Overrides the default feed Accept header
+	Referer     string            // Sent as the Referer header
+	Headers     map[string]string // Extra request headers
+	Cookies     map[string]string // Sent as a Cookie header
+	Query       map[string]string // Added to the feed URL, e.g. partner tokens
+	BearerToken string            // Sent as "Authorization: Bearer <token>"
+}
+
+const DefaultProfile = "bot"
+
+var Profiles = map[string]FetchProfile{
+	"bot": {
+		UserAgent: "bot",
+	},
+	"reader": {
+		UserAgent: "reader",
+	},
+	// Looks like a top-level navigation from a desktop Chrome
+	"browser": {
+		UserAgent: "chrome",
+		Headers: map[string]string{
+			"Sec-Fetch-Dest":            "document",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Fetch-Site":            "none",
+			"Sec-Fetch-User":            "?1",
+			"Upgrade-Insecure-Requests": "1",
+			"Sec-Ch-Ua":                 `"Not;A=Brand";v="99", "Google Chrome";v="139", "Chromium";v="139"`,
+			"Sec-Ch-Ua-Mobile":          "?0",
+			"Sec-Ch-Ua-Platform":        `"macOS"`,
+		},
+	},
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"numerosnumerosnumeros_agg/dynamo"
 	"numerosnumerosnumeros_agg/feeds"
@@ -65,7 +68,7 @@ func collectUnpublished(
 ) ([]typesPkg.MainStruct, error) {
 	toPublish := make([]typesPkg.MainStruct, 0, len(articles))
 	for _, art := range articles {
-		pub, err := dynamo.IsArticlePublished(ctx, db, art.GUID)
+		pub, err := isPublished(ctx, db, art)
 		if err != nil {
 			logger.Error("is-published check failed", zap.Error(err), zap.String("guid", art.GUID))
 			continue
@@ -78,81 +81,637 @@ func collectUnpublished(
 	return toPublish, nil
 }
 
+// isPublished reports whether the article is known under any of its keys.
+func isPublished(ctx context.Context, db *dynamodb.Client, art typesPkg.MainStruct) (bool, error) {
+	for _, guid := range append([]string{art.GUID}, art.AltGUIDs...) {
+		pub, err := dynamo.IsArticlePublished(ctx, db, guid)
+		if err != nil || pub {
+			return pub, err
+		}
+	}
+	return false, nil
+}
+
 // *
 // **
 // ***
 // ****
 // ***** main
 type feedResult struct {
-	Articles []typesPkg.MainStruct
-	Err      error
+	Articles          []typesPkg.MainStruct
+	Validators        typesPkg.FeedValidators
+	ValidatorsChanged bool
+	HighWater         time.Time
+	HighWaterChanged  bool
+	Err               error
 }
 
-func runParsers(ctx context.Context, db *dynamodb.Client) error {
-	email := os.Getenv("MAIN_EMAIL")
-	if email == "" {
-		return fmt.Errorf("MAIN_EMAIL not set")
+// Validators and high-water marks are only persisted once the articles they
+// cover have been published, otherwise a failed send would be hidden behind
+// a 304 (or the high-water filter) next run.
+func saveFeedProgress(ctx context.Context, db *dynamodb.Client, results []feedResult) {
+	for i, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		url := feeds.Feeds[i].URL
+		if res.ValidatorsChanged {
+			if err := dynamo.SaveFeedValidators(ctx, db, url, res.Validators); err != nil {
+				logger.Error("Error saving feed validators",
+					zap.String("url", url),
+					zap.Error(err),
+				)
+			}
+		}
+		if res.HighWaterChanged {
+			if err := dynamo.SaveFeedHighWater(ctx, db, url, res.HighWater); err != nil {
+				logger.Error("Error saving feed high-water mark",
+					zap.String("url", url),
+					zap.Error(err),
+				)
+			}
+		}
 	}
+}
 
-	userAgents := typesPkg.Agents{
-		Bot:    "numerosnumerosnumeros_bot/1.0 (+https://numerosnumerosnumeros.com; " + email + ")",
-		Chrome: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36",
-		Reader: "RSSReader/1.0 (+https://numerosnumerosnumeros.com; " + email + ")",
+// dropStale removes items published before the feed's high-water mark or
+// longer ago than maxAge. Undated items are kept, the published check
+// handles them.
+func dropStale(articles []typesPkg.MainStruct, highWater time.Time, maxAge time.Duration, now time.Time) ([]typesPkg.MainStruct, int) {
+	cutoff := now.Add(-maxAge)
+	if highWater.After(cutoff) {
+		cutoff = highWater
 	}
 
-	results := make([]feedResult, len(feeds.Feeds))
-	var wg sync.WaitGroup
+	kept := make([]typesPkg.MainStruct, 0, len(articles))
+	for _, art := range articles {
+		if !art.Published.IsZero() && art.Published.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, art)
+	}
+	return kept, len(articles) - len(kept)
+}
 
-	for idx, cfg := range feeds.Feeds {
-		wg.Add(1)
-		go func(i int, fc feeds.FeedConfig) {
-			defer wg.Done()
+// keepQualified drops items below the feed's score, comment and age
+// thresholds. They stay unpublished, so they are reconsidered on the next
+// run.
+func keepQualified(articles []typesPkg.MainStruct, fc feeds.FeedConfig, now time.Time) []typesPkg.MainStruct {
+	kept := make([]typesPkg.MainStruct, 0, len(articles))
+	for _, art := range articles {
+		if fc.MinScore > 0 && art.Score < fc.MinScore {
+			continue
+		}
+		if fc.MinComments > 0 && art.Comments < fc.MinComments {
+			continue
+		}
+		if fc.MinAge > 0 && (art.Published.IsZero() || now.Sub(art.Published) < fc.MinAge) {
+			continue
+		}
+		kept = append(kept, art)
+	}
+	return kept
+}
 
-			articles, err := tools.ParseRSSFeed(ctx, userAgents, fc)
-			if err != nil {
-				logger.Error("Error parsing RSS feed",
+// newestPublished returns the latest publish date among articles, clamped to
+// now so a feed with a broken clock can't block its own future items.
+func newestPublished(articles []typesPkg.MainStruct, now time.Time) time.Time {
+	var newest time.Time
+	for _, art := range articles {
+		if art.Published.After(newest) {
+			newest = art.Published
+		}
+	}
+	if newest.After(now) {
+		newest = now
+	}
+	return newest
+}
+
+// sortOldestFirst posts a feed's items in publication order. Feeds that
+// don't date every item keep their own order.
+func sortOldestFirst(articles []typesPkg.MainStruct) {
+	for _, art := range articles {
+		if art.Published.IsZero() {
+			return
+		}
+	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].Published.Before(articles[j].Published)
+	})
+}
+
+var errCircuitOpen = errors.New("feed skipped while its circuit is open")
+
+func saveHealth(ctx context.Context, db *dynamodb.Client, fc feeds.FeedConfig, health typesPkg.FeedHealth) {
+	if err := dynamo.SaveFeedHealth(ctx, db, fc.URL, health); err != nil {
+		logger.Error("Error saving feed health",
+			zap.String("url", fc.URL),
+			zap.Error(err),
+		)
+	}
+}
+
+func processFeed(
+	ctx context.Context,
+	db *dynamodb.Client,
+	fetcher *tools.Fetcher,
+	userAgents typesPkg.Agents,
+	fc feeds.FeedConfig,
+) feedResult {
+	var res feedResult
+
+	state, err := dynamo.GetFeedState(ctx, db, fc.URL)
+	stateLoaded := err == nil
+	if err != nil {
+		logger.Warn("Error loading feed state",
+			zap.String("url", fc.URL),
+			zap.Error(err),
+		)
+	}
+
+	now := time.Now()
+	if tools.CircuitOpen(state.Health, now) {
+		logger.Info("Skipping feed while its circuit is open",
+			zap.String("url", fc.URL),
+			zap.Int("consecutive_failures", state.Health.ConsecutiveFailures),
+			zap.Time("open_until", state.Health.OpenUntil),
+			zap.String("last_error", state.Health.LastError),
+		)
+		res.Err = errCircuitOpen
+		return res
+	}
+
+	var jar *tools.CookieJar
+	if fc.CookieJar {
+		jar = tools.NewCookieJar(state.Cookies)
+	}
+
+	parsed, err := tools.ParseRSSFeed(ctx, fetcher, userAgents, fc, state.Validators, jar)
+
+	// Cookies are kept even when the fetch failed: consent walls usually hand
+	// them out on the very response that couldn't be parsed
+	if jar != nil {
+		if cookies, changed := jar.Snapshot(); changed {
+			if err := dynamo.SaveFeedCookies(ctx, db, fc.URL, cookies); err != nil {
+				logger.Error("Error saving feed cookies",
 					zap.String("url", fc.URL),
 					zap.Error(err),
 				)
-				results[i].Err = err
-				return
 			}
+		}
+	}
 
-			toPub, err := collectUnpublished(ctx, articles, db)
+	if errors.Is(err, tools.ErrDisallowedByRobots) {
+		logger.Warn("Skipping feed disallowed by robots.txt",
+			zap.String("url", fc.URL),
+			zap.String("source", fc.Header),
+		)
+		res.Err = err
+		return res
+	}
+	if err != nil && ctx.Err() != nil {
+		// Cancelled or out of time: not the feed's fault, leave its health alone.
+		logger.Warn("Feed fetch interrupted",
+			zap.String("url", fc.URL),
+			zap.Error(err),
+		)
+		res.Err = err
+		return res
+	}
+	if err != nil {
+		health := tools.RecordFailure(state.Health, now, err)
+		logger.Error("Error parsing RSS feed",
+			zap.String("url", fc.URL),
+			zap.Int("consecutive_failures", health.ConsecutiveFailures),
+			zap.Error(err),
+		)
+		if tools.CircuitOpen(health, now) {
+			logger.Warn("Feed circuit opened",
+				zap.String("url", fc.URL),
+				zap.Time("open_until", health.OpenUntil),
+			)
+		}
+		saveHealth(ctx, db, fc, health)
+		res.Err = err
+		return res
+	}
+
+	parsed.Posts, err = tools.PreparePosts(parsed.Posts, fc)
+	if err == nil {
+		parsed.Posts, err = tools.ApplyGUIDStrategy(parsed.Posts, fc)
+	}
+	if err != nil {
+		logger.Error("Error post-processing feed items",
+			zap.String("url", fc.URL),
+			zap.Error(err),
+		)
+		res.Err = err
+		return res
+	}
+
+	for _, warning := range parsed.Warnings {
+		logger.Warn("Feed parsed with warnings",
+			zap.String("url", fc.URL),
+			zap.String("warning", warning),
+		)
+	}
+
+	itemCount := len(parsed.Posts)
+	if parsed.NotModified {
+		itemCount = state.Health.LastItemCount
+	}
+
+	res.Validators = parsed.Validators
+	res.ValidatorsChanged = parsed.Validators != state.Validators
+
+	// A feed that has never been fetched successfully is seeded: everything
+	// it currently lists is marked published without being sent, so adding
+	// a feed doesn't flood the channel with its backlog
+	if stateLoaded && !parsed.NotModified && state.Health.LastSuccess.IsZero() && state.HighWater.IsZero() {
+		if err := dynamo.BatchMarkPublished(ctx, db, parsed.Posts); err != nil {
+			logger.Error("Error seeding new feed",
+				zap.String("url", fc.URL),
+				zap.Error(err),
+			)
+			res.Err = err
+			return res
+		}
+		saveHealth(ctx, db, fc, tools.RecordSuccess(state.Health, now, itemCount))
+
+		res.HighWater = newestPublished(parsed.Posts, now)
+		res.HighWaterChanged = !res.HighWater.IsZero()
+		logger.Info("Seeded new feed",
+			zap.String("url", fc.URL),
+			zap.Int("items", len(parsed.Posts)),
+		)
+		return res
+	}
+
+	saveHealth(ctx, db, fc, tools.RecordSuccess(state.Health, now, itemCount))
+	if parsed.NotModified {
+		return res
+	}
+
+	maxAge := fc.MaxAge
+	if maxAge <= 0 {
+		maxAge = feeds.DefaultMaxAge
+	}
+	// With thresholds, or on a ranked listing, an older item can qualify
+	// after a newer one was sent, so the high-water mark doesn't apply
+	thresholded := fc.MinScore > 0 || fc.MinComments > 0 || fc.MinAge > 0
+	useHighWater := !thresholded && !tools.RankedListing(fc)
+	highWater := state.HighWater
+	if !useHighWater {
+		highWater = time.Time{}
+	}
+
+	fresh, dropped := dropStale(parsed.Posts, highWater, maxAge, now)
+	if dropped > 0 {
+		logger.Info("Dropped stale feed items",
+			zap.String("url", fc.URL),
+			zap.Int("dropped", dropped),
+			zap.Time("high_water", highWater),
+		)
+	}
+
+	if thresholded {
+		fresh = keepQualified(fresh, fc, now)
+	}
+
+	toPub, err := collectUnpublished(ctx, fresh, db)
+	if err != nil {
+		logger.Error("Error collecting unpublished articles",
+			zap.String("source", fc.Header),
+			zap.Error(err),
+		)
+		res.Err = err
+		return res
+	}
+
+	if fc.ResolveLinks != "" {
+		toPub = resolveLinks(ctx, db, fetcher, userAgents, fc, toPub)
+	}
+
+	if newest := newestPublished(toPub, now); useHighWater && newest.After(state.HighWater) {
+		res.HighWater = newest
+		res.HighWaterChanged = true
+	}
+
+	res.Articles = toPub
+	return res
+}
+
+// resolveLinks replaces each article's link with its resolved canonical URL.
+// Only articles that passed the published check get here, so pages are
+// fetched once. An article whose GUID depends on its link is checked again
+// under the resolved GUID, and keeps the old one in AltGUIDs so the next
+// run skips it without resolving.
+func resolveLinks(
+	ctx context.Context,
+	db *dynamodb.Client,
+	fetcher *tools.Fetcher,
+	userAgents typesPkg.Agents,
+	fc feeds.FeedConfig,
+	articles []typesPkg.MainStruct,
+) []typesPkg.MainStruct {
+	resolved := make([]typesPkg.MainStruct, 0, len(articles))
+	var alreadyPublished []typesPkg.MainStruct
+	failed := 0
+
+	for _, art := range articles {
+		link, err := tools.ResolveLink(ctx, fetcher, userAgents, fc, art.Link)
+		if err != nil {
+			failed++
+			resolved = append(resolved, art)
+			continue
+		}
+		if link == art.Link {
+			resolved = append(resolved, art)
+			continue
+		}
+
+		if guid := tools.GUIDForLink(art, fc, link); guid != art.GUID {
+			art.AltGUIDs = append(art.AltGUIDs, art.GUID)
+			art.GUID = guid
+
+			pub, err := dynamo.IsArticlePublished(ctx, db, art.GUID)
 			if err != nil {
-				logger.Error("Error collecting unpublished articles",
-					zap.String("source", fc.Header),
-					zap.Error(err),
-				)
-				results[i].Err = err
+				logger.Error("is-published check failed", zap.Error(err), zap.String("guid", art.GUID))
+				continue
+			}
+			if pub {
+				alreadyPublished = append(alreadyPublished, art)
+				continue
+			}
+		}
+
+		art.Link = link
+		resolved = append(resolved, art)
+	}
+
+	if failed > 0 {
+		logger.Warn("Some links could not be resolved",
+			zap.String("url", fc.URL),
+			zap.Int("failed", failed),
+		)
+	}
+
+	if len(alreadyPublished) > 0 {
+		if err := dynamo.BatchMarkPublished(ctx, db, alreadyPublished); err != nil {
+			logger.Error("Error marking resolved duplicates",
+				zap.String("url", fc.URL),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return resolved
+}
+
+// *
+// **
+// ***
+// ****
+// ***** near-duplicates
+type storyCandidate struct {
+	art      typesPkg.MainStruct
+	priority int
+	words    []string
+}
+
+// addRelated records art as further coverage of a story, once per outlet.
+func addRelated(related []typesPkg.Coverage, primaryHeader string, art typesPkg.MainStruct) ([]typesPkg.Coverage, bool) {
+	if art.Header == "" || art.Link == "" || art.Header == primaryHeader {
+		return related, false
+	}
+	for _, c := range related {
+		if c.Header == art.Header || c.Link == art.Link {
+			return related, false
+		}
+	}
+	return append(related, typesPkg.Coverage{Header: art.Header, Link: art.Link}), true
+}
+
+// suppressNearDuplicates drops articles whose story words are at least
+// minSimilarity alike to an already posted story or to a candidate from a
+// higher-priority (or, on a tie, earlier) feed. A dropped article becomes
+// related coverage of the story it duplicates: kept candidates carry it in
+// Related, posted stories that gained coverage are returned for editing.
+// The kept articles stay in their original order.
+func suppressNearDuplicates(
+	candidates []storyCandidate,
+	history []typesPkg.Story,
+	minSimilarity float64,
+) ([]typesPkg.MainStruct, []typesPkg.MainStruct, []typesPkg.Story) {
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return candidates[order[a]].priority > candidates[order[b]].priority
+	})
+
+	keep := make([]bool, len(candidates))
+	var kept []int
+	edited := make(map[int]bool)
+
+	for _, i := range order {
+		c := candidates[i]
+		if len(c.words) == 0 || minSimilarity <= 0 {
+			keep[i] = true
+			continue
+		}
+
+		// Already posted stories win over anything in this run
+		matchTitle, matchHeader := "", ""
+		for h := range history {
+			if tools.Similarity(c.words, history[h].Words) < minSimilarity {
+				continue
+			}
+			var added bool
+			history[h].Related, added = addRelated(history[h].Related, history[h].Header, c.art)
+			if added && history[h].MessageID != 0 {
+				edited[h] = true
+			}
+			matchTitle, matchHeader = history[h].Title, history[h].Header
+			break
+		}
+		if matchTitle == "" {
+			for _, k := range kept {
+				if tools.Similarity(c.words, candidates[k].words) < minSimilarity {
+					continue
+				}
+				primary := &candidates[k].art
+				primary.Related, _ = addRelated(primary.Related, primary.Header, c.art)
+				matchTitle, matchHeader = primary.Title, primary.Header
+				break
+			}
+		}
+
+		if matchTitle != "" {
+			logger.Info("Suppressed near-duplicate story",
+				zap.String("guid", c.art.GUID),
+				zap.String("title", c.art.Title),
+				zap.String("source", c.art.Header),
+				zap.String("duplicate_of", matchTitle),
+				zap.String("duplicate_source", matchHeader),
+			)
+			continue
+		}
+
+		keep[i] = true
+		kept = append(kept, i)
+	}
+
+	var send, suppressed []typesPkg.MainStruct
+	for i, c := range candidates {
+		if keep[i] {
+			send = append(send, c.art)
+		} else {
+			suppressed = append(suppressed, c.art)
+		}
+	}
+
+	var edits []typesPkg.Story
+	for h := range history {
+		if edited[h] {
+			edits = append(edits, history[h])
+		}
+	}
+	return send, suppressed, edits
+}
+
+// editRelatedCoverage updates the keyboards of posted stories that gained
+// related coverage, and remembers the new coverage for later arrivals.
+func editRelatedCoverage(ctx context.Context, db *dynamodb.Client, edits []typesPkg.Story, botToken, channelID string) {
+	for i, story := range edits {
+		if err := telegram.EditRelated(ctx, story, botToken, channelID); err != nil {
+			logger.Error("Error editing related coverage",
+				zap.String("guid", story.GUID),
+				zap.Int64("message_id", story.MessageID),
+				zap.Error(err),
+			)
+			continue
+		}
+		if err := dynamo.SaveStoryRelated(ctx, db, story); err != nil {
+			logger.Error("Error saving related coverage",
+				zap.String("guid", story.GUID),
+				zap.Error(err),
+			)
+		}
+
+		if i < len(edits)-1 {
+			if err := tools.SleepContext(ctx, 1500*time.Millisecond); err != nil {
 				return
 			}
+		}
+	}
+}
 
-			results[i].Articles = toPub
+// postedStories pairs sent articles with their message IDs for the story
+// history.
+func postedStories(articles []typesPkg.MainStruct, messageIDs []int64, withDescription bool) []typesPkg.Story {
+	now := time.Now()
+	stories := make([]typesPkg.Story, 0, len(articles))
+	for i, art := range articles {
+		words := tools.StoryWords(art, withDescription)
+		if len(words) == 0 {
+			continue
+		}
+		story := typesPkg.Story{
+			GUID:           art.GUID,
+			Title:          art.Title,
+			Header:         art.Header,
+			Link:           art.Link,
+			Words:          words,
+			Posted:         now,
+			Related:        art.Related,
+			DiscussionLink: art.DiscussionLink,
+		}
+		if i < len(messageIDs) {
+			story.MessageID = messageIDs[i]
+		}
+		stories = append(stories, story)
+	}
+	return stories
+}
+
+func runParsers(ctx context.Context, db *dynamodb.Client) error {
+	email := os.Getenv("MAIN_EMAIL")
+	if email == "" {
+		return fmt.Errorf("MAIN_EMAIL not set")
+	}
+
+	userAgents := tools.NewAgents(email)
+
+	fetcher := tools.NewFetcher(tools.FetcherOptions{Feeds: feeds.Feeds})
+
+	results := make([]feedResult, len(feeds.Feeds))
+	var wg sync.WaitGroup
+
+	for idx, cfg := range feeds.Feeds {
+		wg.Add(1)
+		go func(i int, fc feeds.FeedConfig) {
+			defer wg.Done()
+			results[i] = processFeed(ctx, db, fetcher, userAgents, fc)
 		}(idx, cfg)
 	}
 
 	wg.Wait()
 
 	// Aggregate results preserving feed order
-	allToPublish := make([]typesPkg.MainStruct, 0, 64)
+	candidates := make([]storyCandidate, 0, 64)
+	var suppressed []typesPkg.MainStruct
 	seen := make(map[string]bool, 256)
 
-	for _, res := range results {
+	for i, res := range results {
 		if res.Err != nil {
 			continue
 		}
+		sortOldestFirst(res.Articles)
 		for _, art := range res.Articles {
 			if seen[art.GUID] {
 				continue
 			}
+			if seen[art.Link] {
+				// same article under another feed's GUID
+				suppressed = append(suppressed, art)
+				continue
+			}
 			seen[art.GUID] = true
-			allToPublish = append(allToPublish, art)
+			seen[art.Link] = true
+			candidates = append(candidates, storyCandidate{
+				art:      art,
+				priority: feeds.Feeds[i].Priority,
+				words:    tools.StoryWords(art, feeds.Dedup.WithDescription),
+			})
+		}
+	}
+
+	var history []typesPkg.Story
+	if len(candidates) > 0 && feeds.Dedup.MinSimilarity > 0 {
+		var err error
+		history, err = dynamo.RecentStories(ctx, db, time.Now().Add(-feeds.Dedup.Window))
+		if err != nil {
+			logger.Warn("Error loading recent stories, only deduplicating within this run",
+				zap.Error(err),
+			)
 		}
 	}
 
+	allToPublish, duplicates, edits := suppressNearDuplicates(candidates, history, feeds.Dedup.MinSimilarity)
+	suppressed = append(suppressed, duplicates...)
+
 	// Nothing new -> done
-	if len(allToPublish) == 0 {
+	if len(allToPublish) == 0 && len(edits) == 0 {
+		if err := dynamo.BatchMarkPublished(ctx, db, suppressed); err != nil {
+			logger.Error("Error marking suppressed duplicates",
+				zap.Int("count", len(suppressed)), zap.Error(err),
+			)
+			return err
+		}
+		saveFeedProgress(ctx, db, results)
 		return nil
 	}
 
@@ -166,7 +725,7 @@ func runParsers(ctx context.Context, db *dynamodb.Client) error {
 		return fmt.Errorf("TELEGRAM_CHANNEL not set")
 	}
 
-	err := telegram.SendMessages(allToPublish, telegramBot, telegramChannel)
+	messageIDs, err := telegram.SendMessages(ctx, allToPublish, telegramBot, telegramChannel)
 	if err != nil {
 		logger.Error("Error sending messages",
 			zap.Error(err),
@@ -174,15 +733,32 @@ func runParsers(ctx context.Context, db *dynamodb.Client) error {
 		return err
 	}
 
-	// Mark published
-	if err := dynamo.BatchMarkPublished(ctx, db, allToPublish); err != nil {
+	// Mark published, duplicates included so they aren't reconsidered
+	if err := dynamo.BatchMarkPublished(ctx, db, append(allToPublish, suppressed...)); err != nil {
 		logger.Error("BatchMarkPublished failed after send",
-			zap.Int("count", len(allToPublish)), zap.Error(err),
+			zap.Int("count", len(allToPublish)+len(suppressed)), zap.Error(err),
 		)
 		return err
 	}
 
-	logger.Info("Run complete", zap.Int("new_articles", len(allToPublish)))
+	if feeds.Dedup.MinSimilarity > 0 && len(allToPublish) > 0 {
+		stories := postedStories(allToPublish, messageIDs, feeds.Dedup.WithDescription)
+		if err := dynamo.SaveStories(ctx, db, stories); err != nil {
+			logger.Error("Error saving posted stories",
+				zap.Error(err),
+			)
+		}
+	}
+
+	editRelatedCoverage(ctx, db, edits, telegramBot, telegramChannel)
+
+	saveFeedProgress(ctx, db, results)
+
+	logger.Info("Run complete",
+		zap.Int("new_articles", len(allToPublish)),
+		zap.Int("suppressed", len(suppressed)),
+		zap.Int("related_edits", len(edits)),
+	)
 
 	return nil
 }
@@ -1,7 +1,7 @@
 package telegram
 
 import (
-	"math/rand"
+	"context"
 	"numerosnumerosnumeros_agg/tools"
 	"numerosnumerosnumeros_agg/typesPkg"
 	"strconv"
@@ -18,12 +18,17 @@ import (
 
 const telegramMaxLen = 4096
 
-const (
-	maxAttempts  = 3
-	baseBackoff  = 1 * time.Second
-	maxBackoff   = 12 * time.Second
-	jitterPctNum = 20 // +/- up to 20% jitter
-)
+// Related coverage rows after Boost / Read; Telegram allows far more, but
+// the post should stay readable
+const maxRelatedButtons = 6
+
+var retryPolicy = tools.RetryPolicy{
+	MaxAttempts:   3,
+	BaseBackoff:   1 * time.Second,
+	MaxBackoff:    12 * time.Second,
+	MaxRetryAfter: 60 * time.Second,
+	JitterPct:     20, // +/- up to 20% jitter
+}
 
 type LinkPreviewOptions struct {
 	IsDisabled       *bool  `json:"is_disabled,omitempty"`
@@ -42,6 +47,13 @@ type tgAPIError struct {
 	} `json:"parameters"`
 }
 
+type tgMessageResult struct {
+	OK     bool `json:"ok"`
+	Result struct {
+		MessageID int64 `json:"message_id"`
+	} `json:"result"`
+}
+
 func boolp(b bool) *bool { return &b }
 
 func buildLinkPreviewOptionsJSON(p typesPkg.MainStruct) (string, error) {
@@ -63,13 +75,16 @@ func buildLinkPreviewOptionsJSON(p typesPkg.MainStruct) (string, error) {
 	return string(b), nil
 }
 
-func SendMessages(posts []typesPkg.MainStruct, botToken, channelID string) error {
+// SendMessages posts each article and returns the message IDs of the posts
+// that were sent, in order. On error the IDs cover the posts sent so far.
+func SendMessages(ctx context.Context, posts []typesPkg.MainStruct, botToken, channelID string) ([]int64, error) {
 	if len(posts) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
 	client := &http.Client{Timeout: 15 * time.Second}
+	messageIDs := make([]int64, 0, len(posts))
 
 	for i, p := range posts {
 		text := buildTelegramHTML(p)
@@ -88,85 +103,121 @@ func SendMessages(posts []typesPkg.MainStruct, botToken, channelID string) error
 			form.Set("link_preview_options", lpoJSON)
 		}
 
-		if err := postWithRetry(client, endpoint, form, p.GUID); err != nil {
-			return err
+		body, err := postWithRetry(ctx, client, endpoint, form, p.GUID)
+		if err != nil {
+			return messageIDs, err
 		}
 
+		var sent tgMessageResult
+		_ = json.Unmarshal(body, &sent)
+		messageIDs = append(messageIDs, sent.Result.MessageID)
+
 		if i < len(posts)-1 {
-			time.Sleep(1500 * time.Millisecond)
+			if err := tools.SleepContext(ctx, 1500*time.Millisecond); err != nil {
+				return messageIDs, fmt.Errorf("stopped before GUID %q: %w", posts[i+1].GUID, err)
+			}
 		}
 	}
-	return nil
+	return messageIDs, nil
+}
+
+// EditRelated rebuilds the keyboard of an already posted story so it lists
+// its current related coverage.
+func EditRelated(ctx context.Context, story typesPkg.Story, botToken, channelID string) error {
+	if story.MessageID == 0 {
+		return fmt.Errorf("story %q has no message to edit", story.GUID)
+	}
+
+	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/editMessageReplyMarkup", botToken)
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	replyMarkup, err := buildInlineKeyboard(typesPkg.MainStruct{
+		GUID:           story.GUID,
+		Link:           story.Link,
+		Related:        story.Related,
+		DiscussionLink: story.DiscussionLink,
+	}, channelID)
+	if err != nil {
+		return fmt.Errorf("build keyboard for GUID %q: %w", story.GUID, err)
+	}
+
+	form := url.Values{}
+	form.Set("chat_id", channelID)
+	form.Set("message_id", strconv.FormatInt(story.MessageID, 10))
+	form.Set("reply_markup", replyMarkup)
+
+	_, err = postWithRetry(ctx, client, endpoint, form, story.GUID)
+	return err
 }
 
-func postWithRetry(client *http.Client, endpoint string, form url.Values, guid string) error {
+// postWithRetry calls a Bot API method and returns the response body.
+func postWithRetry(ctx context.Context, client *http.Client, endpoint string, form url.Values, guid string) ([]byte, error) {
+	method := endpoint[strings.LastIndex(endpoint, "/")+1:]
 	var lastErr error
 
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		resp, err := client.PostForm(endpoint, form)
+	for attempt := 1; attempt <= retryPolicy.MaxAttempts; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("%s request for GUID %q: %w", method, guid, err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var retryAfter time.Duration
+
+		resp, err := client.Do(req)
 		if err != nil {
 			// network issue -> retryable
-			lastErr = fmt.Errorf("sendMessage failed for GUID %q: %w", guid, err)
-			if attempt < maxAttempts {
-				time.Sleep(backoffDelay(attempt))
-				continue
+			lastErr = fmt.Errorf("%s failed for GUID %q: %w", method, guid, err)
+			if ctx.Err() != nil {
+				return nil, lastErr
 			}
-			return lastErr
-		}
+		} else {
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
 
-		body, _ := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return body, nil
+			}
 
-		if resp.StatusCode == http.StatusOK {
-			return nil
-		}
+			apiErr := tgAPIError{}
+			_ = json.Unmarshal(body, &apiErr)
 
-		apiErr := tgAPIError{}
-		_ = json.Unmarshal(body, &apiErr)
+			status := resp.StatusCode
+			if apiErr.ErrorCode == http.StatusTooManyRequests {
+				status = http.StatusTooManyRequests
+			}
 
-		// Respect explicit retry_after if present (FloodWait / rate limit)
-		if (resp.StatusCode == http.StatusTooManyRequests || apiErr.ErrorCode == http.StatusTooManyRequests) && apiErr.Parameters.RetryAfter > 0 {
-			if attempt < maxAttempts {
-				time.Sleep(time.Duration(apiErr.Parameters.RetryAfter) * time.Second)
-				continue
+			if !retryPolicy.Retryable(status) {
+				return nil, fmt.Errorf("telegram API status %d: %s", resp.StatusCode, string(body))
 			}
-			return fmt.Errorf("telegram rate limited (retry_after=%ds) for GUID %q: %s", apiErr.Parameters.RetryAfter, guid, string(body))
-		}
 
-		// Respect Retry-After header if provided
-		if ra := resp.Header.Get("Retry-After"); ra != "" {
-			if secs, err := strconv.Atoi(ra); err == nil && secs > 0 && attempt < maxAttempts {
-				time.Sleep(time.Duration(secs) * time.Second)
-				continue
+			// Respect explicit retry_after if present (FloodWait / rate limit), then the header
+			retryAfter = time.Duration(apiErr.Parameters.RetryAfter) * time.Second
+			if retryAfter <= 0 {
+				retryAfter = tools.ParseRetryAfter(resp.Header)
 			}
-		}
 
-		if resp.StatusCode >= 500 && resp.StatusCode <= 599 {
-			lastErr = fmt.Errorf("telegram API status %d: %s", resp.StatusCode, string(body))
-			if attempt < maxAttempts {
-				time.Sleep(backoffDelay(attempt))
-				continue
+			if status == http.StatusTooManyRequests {
+				lastErr = fmt.Errorf("telegram rate limited (retry_after=%v) for GUID %q: %s", retryAfter, guid, string(body))
+			} else {
+				lastErr = fmt.Errorf("telegram API status %d: %s", resp.StatusCode, string(body))
 			}
-			return lastErr
 		}
 
-		return fmt.Errorf("telegram API status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Should not reach here
-	return lastErr
-}
-
-func backoffDelay(attempt int) time.Duration {
-	delay := min(baseBackoff<<(attempt-1), maxBackoff)
+		if attempt == retryPolicy.MaxAttempts {
+			break
+		}
 
-	jitter := int64(delay) * int64(jitterPctNum) / 100
-	if jitter <= 0 {
-		return delay
+		wait, ok := retryPolicy.Delay(attempt, retryAfter)
+		if !ok {
+			return nil, lastErr
+		}
+		if err := tools.SleepContext(ctx, wait); err != nil {
+			return nil, fmt.Errorf("%w (retry aborted: %v)", lastErr, err)
+		}
 	}
 
-	offset := rand.Int63n(2*jitter+1) - jitter
-	return time.Duration(int64(delay) + offset)
+	return nil, lastErr
 }
 
 func buildTelegramHTML(p typesPkg.MainStruct) string {
@@ -234,8 +285,23 @@ func buildInlineKeyboard(p typesPkg.MainStruct, channelID string) (string, error
 	if link != "" {
 		row = append(row, btn{Text: "🔗 Read", URL: link})
 	}
+	if discuss := strings.TrimSpace(p.DiscussionLink); discuss != "" && discuss != link {
+		row = append(row, btn{Text: "💬 Discuss", URL: discuss})
+	}
+
+	rows := [][]btn{row}
+	for i, c := range p.Related {
+		if i == maxRelatedButtons {
+			break
+		}
+		header := strings.TrimSpace(c.Header)
+		if header == "" || strings.TrimSpace(c.Link) == "" {
+			continue
+		}
+		rows = append(rows, []btn{{Text: "📰 " + ensureMaxLen(header, 40), URL: strings.TrimSpace(c.Link)}})
+	}
 
-	m := markup{InlineKeyboard: [][]btn{row}}
+	m := markup{InlineKeyboard: rows}
 	b, err := json.Marshal(m)
 	if err != nil {
 		return "", err
@@ -0,0 +1,13 @@
+package tools
+
+import "numerosnumerosnumeros_agg/typesPkg"
+
+// NewAgents builds the user agents we identify with; email is the contact
+// address advertised to site owners.
+func NewAgents(email string) typesPkg.Agents {
+	return typesPkg.Agents{
+		Bot:    "numerosnumerosnumeros_bot/1.0 (+https://numerosnumerosnumeros.com; " + email + ")",
+		Chrome: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36",
+		Reader: "RSSReader/1.0 (+https://numerosnumerosnumeros.com; " + email + ")",
+	}
+}
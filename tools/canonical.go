@@ -0,0 +1,225 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+
+	"golang.org/x/net/html"
+)
+
+const maxCanonicalPageBytes = 512 << 10
+
+// Query parameters that only track where a click came from
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"mc_cid": true, "mc_eid": true, "igshid": true, "_hsenc": true, "_hsmi": true,
+	"mkt_tok": true, "ref_src": true, "ref_url": true, "cmpid": true, "ocid": true,
+	"smid": true, "smtyp": true, "sr_share": true, "wt.mc_id": true,
+	"__twitter_impression": true,
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || trackingParams[name]
+}
+
+// CleanURL canonicalises a link without touching the network: lowercase
+// scheme and host, no default port, no fragment, no tracking parameters and
+// no trailing slash. Links that don't parse as absolute http(s) URLs are
+// returned unchanged.
+func CleanURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return raw
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		u.Host = host + ":" + port
+	} else {
+		u.Host = host
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	// Filter the raw query so the remaining parameters keep their order and
+	// encoding
+	if u.RawQuery != "" {
+		kept := make([]string, 0, 4)
+		for _, pair := range strings.Split(u.RawQuery, "&") {
+			if pair == "" {
+				continue
+			}
+			name, _, _ := strings.Cut(pair, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			if isTrackingParam(name) {
+				continue
+			}
+			kept = append(kept, pair)
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+	u.ForceQuery = false
+
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		if u.RawPath != "" {
+			u.RawPath = strings.TrimRight(u.RawPath, "/")
+		}
+	}
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	return u.String()
+}
+
+// CleanLinks applies CleanURL to every post's link. A GUID that is just the
+// link follows it, so dedup works on the cleaned URL; the raw link stays in
+// AltGUIDs for records written before links were cleaned.
+func CleanLinks(posts []typesPkg.MainStruct) []typesPkg.MainStruct {
+	for i := range posts {
+		cleaned := CleanURL(posts[i].Link)
+		if posts[i].GUID == posts[i].Link {
+			if cleaned != posts[i].GUID {
+				posts[i].AltGUIDs = append(posts[i].AltGUIDs, posts[i].GUID)
+			}
+			posts[i].GUID = cleaned
+		}
+		posts[i].Link = cleaned
+	}
+	return posts
+}
+
+// ResolveLink follows redirects from link and, in "canonical" mode, reads the
+// page's <link rel="canonical">. The result is passed through CleanURL. The
+// feed's proxy and user agent are used, but none of its profile's headers,
+// cookies or tokens, and robots.txt is honoured for the article's host.
+func ResolveLink(ctx context.Context, fetcher *Fetcher, userAgents typesPkg.Agents, feed feeds.FeedConfig, link string) (string, error) {
+	mode := feed.ResolveLinks
+	if mode != "redirect" && mode != "canonical" {
+		return "", fmt.Errorf("unknown link resolution mode %q", mode)
+	}
+
+	page := feed
+	page.URL = link
+
+	release, err := fetcher.acquire(ctx, page)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	profile, err := resolveProfile(feed)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := fetcher.clientFor(feed)
+	if err != nil {
+		return "", err
+	}
+
+	if err := fetcher.checkRobots(ctx, client, link, userAgents.Bot); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", profileUserAgent(profile, userAgents))
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	resp, err := FeedRetryPolicy.Do(ctx, client, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	final := resp.Request.URL
+	if mode == "canonical" {
+		canonical, err := parseCanonicalLink(io.LimitReader(resp.Body, maxCanonicalPageBytes), final)
+		if err != nil {
+			return "", err
+		}
+		if canonical != nil {
+			final = canonical
+		}
+	}
+
+	return CleanURL(final.String()), nil
+}
+
+// parseCanonicalLink returns the first http(s) <link rel="canonical"> in the
+// document head, or nil when there is none.
+func parseCanonicalLink(r io.Reader, base *url.URL) (*url.URL, error) {
+	tokenizer := html.NewTokenizer(r)
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("failed to parse HTML: %w", err)
+			}
+			return nil, nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if string(name) == "body" {
+				return nil, nil
+			}
+			if string(name) != "link" {
+				continue
+			}
+
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = tokenizer.TagAttr()
+				attrs[string(key)] = string(val)
+			}
+
+			href := strings.TrimSpace(attrs["href"])
+			if !hasToken(attrs["rel"], "canonical") || href == "" {
+				continue
+			}
+			canonical, err := base.Parse(href)
+			if err != nil || (canonical.Scheme != "http" && canonical.Scheme != "https") {
+				continue
+			}
+			return canonical, nil
+
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "head" {
+				return nil, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var xmlEncodingDecl = regexp.MustCompile(`^\s*<\?xml[^>]*?encoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// lookupCharset resolves a charset label (as used in Content-Type headers and
+// XML declarations) to an encoding. A nil encoding means UTF-8.
+func lookupCharset(label string) (encoding.Encoding, error) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" || label == "utf-8" || label == "utf8" {
+		return nil, nil
+	}
+
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", label)
+	}
+	if enc == unicode.UTF8 {
+		return nil, nil
+	}
+	return enc, nil
+}
+
+func contentTypeCharset(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+// utf8Reader transcodes r to UTF-8 when the charset can be determined before
+// XML decoding starts. Precedence is BOM, then the XML declaration (left to
+// the decoder's CharsetReader), then the Content-Type charset.
+// The returned bool reports whether the stream is already UTF-8, in which
+// case any encoding named by the XML declaration must be ignored.
+func utf8Reader(r io.Reader, contentType string) (io.Reader, bool, error) {
+	br := bufio.NewReader(r)
+
+	head, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(head, []byte{0xef, 0xbb, 0xbf}):
+		_, _ = br.Discard(3)
+		return br, true, nil
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}), bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		dec := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+		return transform.NewReader(br, unicode.BOMOverride(dec)), true, nil
+	}
+
+	prolog, _ := br.Peek(512)
+	if xmlEncodingDecl.Match(prolog) {
+		return br, false, nil
+	}
+
+	enc, err := lookupCharset(contentTypeCharset(contentType))
+	if err != nil {
+		return nil, false, err
+	}
+	if enc == nil {
+		return br, true, nil
+	}
+	return transform.NewReader(br, enc.NewDecoder()), true, nil
+}
+
+func charsetReader(alreadyUTF8 bool) func(string, io.Reader) (io.Reader, error) {
+	return func(label string, input io.Reader) (io.Reader, error) {
+		if alreadyUTF8 {
+			return input, nil
+		}
+		enc, err := lookupCharset(label)
+		if err != nil {
+			return nil, err
+		}
+		if enc == nil {
+			return input, nil
+		}
+		return transform.NewReader(input, enc.NewDecoder()), nil
+	}
+}
+
+func newXMLDecoder(r io.Reader, contentType string) (*xml.Decoder, error) {
+	r, alreadyUTF8, err := utf8Reader(r, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charsetReader(alreadyUTF8)
+	return decoder, nil
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+// decodeText returns the character data of a one-element XML document.
+func decodeText(t *testing.T, doc, contentType string) (string, error) {
+	t.Helper()
+	decoder, err := newXMLDecoder(strings.NewReader(doc), contentType)
+	if err != nil {
+		return "", err
+	}
+	var v struct {
+		Text string `xml:",chardata"`
+	}
+	err = decoder.Decode(&v)
+	return v.Text, err
+}
+
+func TestXMLDecoderCharset(t *testing.T) {
+	const (
+		latin1 = "Caf\xe9 cr\xe8me"
+		utf8   = "Café crème"
+	)
+	tests := []struct {
+		name        string
+		doc         string
+		contentType string
+	}{
+		{"utf-8 without hints", `<t>` + utf8 + `</t>`, ""},
+		{"iso-8859-1 declaration", `<?xml version="1.0" encoding="ISO-8859-1"?><t>` + latin1 + `</t>`, ""},
+		{"windows-1252 declaration", `<?xml version='1.0' encoding='windows-1252'?><t>` + latin1 + `</t>`, ""},
+		{"content-type charset", `<t>` + latin1 + `</t>`, "application/rss+xml; charset=ISO-8859-1"},
+		{"declaration beats content-type", `<?xml version="1.0" encoding="ISO-8859-1"?><t>` + latin1 + `</t>`, "text/xml; charset=utf-8"},
+		{"content-type ignored against declaration", `<?xml version="1.0" encoding="utf-8"?><t>` + utf8 + `</t>`, "text/xml; charset=ISO-8859-1"},
+		{"utf-8 bom beats declaration", "\xef\xbb\xbf" + `<?xml version="1.0" encoding="ISO-8859-1"?><t>` + utf8 + `</t>`, ""},
+		{"utf-8 bom beats content-type", "\xef\xbb\xbf<t>" + utf8 + `</t>`, "text/xml; charset=windows-1252"},
+		{"utf-16le bom", "\xff\xfe" + utf16LE(`<?xml version="1.0" encoding="UTF-16"?><t>`+utf8+`</t>`), ""},
+	}
+	for _, tt := range tests {
+		got, err := decodeText(t, tt.doc, tt.contentType)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != utf8 {
+			t.Errorf("%s: got %q, want %q", tt.name, got, utf8)
+		}
+	}
+}
+
+func TestXMLDecoderUnknownCharset(t *testing.T) {
+	tests := []struct {
+		doc, contentType string
+	}{
+		{`<t>x</t>`, "text/xml; charset=x-klingon"},
+		{`<?xml version="1.0" encoding="x-klingon"?><t>x</t>`, ""},
+	}
+	for _, tt := range tests {
+		_, err := decodeText(t, tt.doc, tt.contentType)
+		if err == nil || !strings.Contains(err.Error(), `unsupported charset "x-klingon"`) {
+			t.Errorf("%s %q: err = %v, want unsupported charset", tt.doc, tt.contentType, err)
+		}
+	}
+}
+
+func utf16LE(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		b.WriteByte(byte(r))
+		b.WriteByte(byte(r >> 8))
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+// Session cookies have no expiry of their own, but runs are separate Lambda
+// invocations, so they are kept for a while instead of being dropped.
+const sessionCookieLifetime = 7 * 24 * time.Hour
+
+// CookieJar is a small http.CookieJar whose contents can be saved between
+// runs. It is meant for a single feed, so it stays deliberately simple.
+type CookieJar struct {
+	mu      sync.Mutex
+	cookies []typesPkg.StoredCookie
+	changed bool
+}
+
+func NewCookieJar(saved []typesPkg.StoredCookie) *CookieJar {
+	now := time.Now()
+	jar := &CookieJar{}
+	for _, c := range saved {
+		if c.Expires.After(now) {
+			jar.cookies = append(jar.cookies, c)
+		} else {
+			jar.changed = true
+		}
+	}
+	return jar
+}
+
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+
+	for _, c := range cookies {
+		stored := typesPkg.StoredCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HostOnly: c.Domain == "",
+		}
+
+		stored.Domain = host
+		if !stored.HostOnly {
+			domain, hostOnly, ok := cookieDomain(host, c.Domain)
+			if !ok {
+				continue // a site may not set cookies for somebody else
+			}
+			stored.Domain = domain
+			stored.HostOnly = hostOnly
+		}
+
+		if stored.Path == "" || !strings.HasPrefix(stored.Path, "/") {
+			stored.Path = defaultCookiePath(u.Path)
+		}
+
+		expired := false
+		switch {
+		case c.MaxAge < 0:
+			expired = true
+		case c.MaxAge > 0:
+			stored.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			stored.Expires = c.Expires
+			expired = !c.Expires.After(now)
+		default:
+			stored.Expires = now.Add(sessionCookieLifetime)
+		}
+
+		j.remove(stored.Name, stored.Domain, stored.Path)
+		if !expired {
+			j.cookies = append(j.cookies, stored)
+		}
+		j.changed = true
+	}
+}
+
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	reqPath := u.EscapedPath()
+	if reqPath == "" {
+		reqPath = "/"
+	}
+	now := time.Now()
+
+	var out []*http.Cookie
+	for _, c := range j.cookies {
+		if !c.Expires.After(now) {
+			continue
+		}
+		if c.Secure && u.Scheme != "https" {
+			continue
+		}
+		if c.HostOnly && host != c.Domain {
+			continue
+		}
+		if !c.HostOnly && !domainMatch(host, c.Domain) {
+			continue
+		}
+		if !pathMatch(reqPath, c.Path) {
+			continue
+		}
+		out = append(out, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return out
+}
+
+// Snapshot returns the unexpired cookies and whether anything changed since
+// the jar was loaded.
+func (j *CookieJar) Snapshot() ([]typesPkg.StoredCookie, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	out := make([]typesPkg.StoredCookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		if c.Expires.After(now) {
+			out = append(out, c)
+		}
+	}
+	return out, j.changed
+}
+
+func (j *CookieJar) remove(name, domain, cookiePath string) {
+	kept := j.cookies[:0]
+	for _, c := range j.cookies {
+		if c.Name == name && c.Domain == domain && c.Path == cookiePath {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	j.cookies = kept
+}
+
+// cookieDomain validates a Domain attribute the way net/http/cookiejar does:
+// it must cover the request host and must not be a public suffix such as
+// "co.uk". A suffix or IP address naming the host itself is kept host-only.
+func cookieDomain(host, attr string) (domain string, hostOnly, ok bool) {
+	domain = strings.TrimPrefix(strings.ToLower(attr), ".")
+	if domain == "" || !domainMatch(host, domain) {
+		return "", false, false
+	}
+	if net.ParseIP(host) != nil || isPublicSuffix(domain) {
+		return host, true, domain == host
+	}
+	return domain, false, true
+}
+
+func isPublicSuffix(domain string) bool {
+	ps, _ := publicsuffix.PublicSuffix(domain)
+	return ps == domain
+}
+
+func domainMatch(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func pathMatch(reqPath, cookiePath string) bool {
+	if reqPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(reqPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || reqPath[len(cookiePath)] == '/'
+}
+
+func defaultCookiePath(reqPath string) string {
+	if reqPath == "" || reqPath[0] != '/' {
+		return "/"
+	}
+	dir := path.Dir(reqPath)
+	if dir == "." {
+		return "/"
+	}
+	return dir
+}
@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func cookieNames(cookies []*http.Cookie) map[string]bool {
+	names := make(map[string]bool, len(cookies))
+	for _, c := range cookies {
+		names[c.Name] = true
+	}
+	return names
+}
+
+func TestCookieJarMatching(t *testing.T) {
+	jar := NewCookieJar(nil)
+	jar.SetCookies(mustURL(t, "https://www.example.com/news/feed.xml"), []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "1", Domain: ".example.com", Path: "/"},
+		{Name: "path", Value: "1", Path: "/news"},
+		{Name: "secure", Value: "1", Path: "/", Secure: true},
+		{Name: "other", Value: "1", Domain: "other.com"},
+		{Name: "suffix", Value: "1", Domain: "com"},
+		{Name: "expired", Value: "1", Path: "/", Expires: time.Now().Add(-time.Hour)},
+		{Name: "deleted", Value: "1", Path: "/", MaxAge: -1},
+	})
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://www.example.com/news/feed.xml", []string{"host", "domain", "path", "secure"}},
+		{"https://www.example.com/news", []string{"host", "domain", "path", "secure"}},
+		{"https://www.example.com/newsletter", []string{"domain", "secure"}},
+		{"http://www.example.com/news/a", []string{"host", "domain", "path"}},
+		{"https://example.com/news/a", []string{"domain"}},
+		{"https://sub.www.example.com/", []string{"domain"}},
+		{"https://other.com/", nil},
+		{"https://notexample.com/", nil},
+	}
+	for _, tt := range tests {
+		got := cookieNames(jar.Cookies(mustURL(t, tt.url)))
+		if len(got) != len(tt.want) {
+			t.Errorf("Cookies(%s) = %v, want %v", tt.url, got, tt.want)
+			continue
+		}
+		for _, name := range tt.want {
+			if !got[name] {
+				t.Errorf("Cookies(%s) = %v, want %v", tt.url, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCookieDomain(t *testing.T) {
+	tests := []struct {
+		host, attr   string
+		wantDomain   string
+		wantHostOnly bool
+		wantOK       bool
+	}{
+		{"www.example.com", ".example.com", "example.com", false, true},
+		{"www.example.com", "WWW.Example.com", "www.example.com", false, true},
+		{"www.example.com", "other.com", "", false, false},
+		{"www.example.com", "com", "", false, false},
+		{"news.bbc.co.uk", "co.uk", "", false, false},
+		{"news.bbc.co.uk", "bbc.co.uk", "bbc.co.uk", false, true},
+		{"foo.github.io", "github.io", "", false, false},
+		{"co.uk", "co.uk", "co.uk", true, true},
+		{"192.168.0.1", "192.168.0.1", "192.168.0.1", true, true},
+		{"192.168.0.1", "168.0.1", "", false, false},
+		{"www.example.com", ".", "", false, false},
+	}
+	for _, tt := range tests {
+		domain, hostOnly, ok := cookieDomain(tt.host, tt.attr)
+		if ok != tt.wantOK || (ok && (domain != tt.wantDomain || hostOnly != tt.wantHostOnly)) {
+			t.Errorf("cookieDomain(%q, %q) = %q, %v, %v; want %q, %v, %v",
+				tt.host, tt.attr, domain, hostOnly, ok, tt.wantDomain, tt.wantHostOnly, tt.wantOK)
+		}
+	}
+}
+
+func TestCookieJarExpiry(t *testing.T) {
+	now := time.Now()
+	jar := NewCookieJar([]typesPkg.StoredCookie{
+		{Name: "live", Value: "1", Domain: "example.com", Path: "/", HostOnly: true, Expires: now.Add(time.Hour)},
+		{Name: "stale", Value: "1", Domain: "example.com", Path: "/", HostOnly: true, Expires: now.Add(-time.Hour)},
+	})
+	u := mustURL(t, "https://example.com/")
+
+	saved, changed := jar.Snapshot()
+	if !changed || len(saved) != 1 || saved[0].Name != "live" {
+		t.Fatalf("after load: %v, changed=%v; want only live, changed", saved, changed)
+	}
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "1"},
+		{Name: "short", Value: "1", MaxAge: 60},
+		{Name: "live", Value: "", MaxAge: -1},
+	})
+	saved, _ = jar.Snapshot()
+	byName := map[string]typesPkg.StoredCookie{}
+	for _, c := range saved {
+		byName[c.Name] = c
+	}
+	if _, ok := byName["live"]; ok {
+		t.Error("MaxAge<0 did not delete the stored cookie")
+	}
+	if exp := byName["session"].Expires; exp.Before(now.Add(sessionCookieLifetime - time.Minute)) {
+		t.Errorf("session cookie expires %v, want about %v from now", exp, sessionCookieLifetime)
+	}
+	if exp := byName["short"].Expires; exp.After(now.Add(2*time.Minute)) || exp.Before(now) {
+		t.Errorf("MaxAge=60 cookie expires %v", exp)
+	}
+	if got := cookieNames(jar.Cookies(u)); len(got) != 2 || !got["session"] || !got["short"] {
+		t.Errorf("Cookies = %v, want session and short", got)
+	}
+}
@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+
+	"golang.org/x/net/html"
+)
+
+const maxDiscoveryPageBytes = 2 << 20
+
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/rdf+xml":   true,
+}
+
+type DiscoveredFeed struct {
+	Config    feeds.FeedConfig
+	Title     string // title attribute of the <link>, if any
+	Type      string
+	ItemCount int
+	Err       error // test-parse failure; Config is still filled in
+}
+
+type feedLink struct {
+	href, title, typ string
+}
+
+// DiscoverFeeds fetches a website, collects its <link rel="alternate"> feed
+// candidates and test-parses each of them with ParseRSSFeed.
+func DiscoverFeeds(ctx context.Context, fetcher *Fetcher, userAgents typesPkg.Agents, pageURL string) ([]DiscoveredFeed, error) {
+	page := feeds.FeedConfig{URL: pageURL}
+
+	release, err := fetcher.acquire(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	links, siteName, err := fetchFeedLinks(ctx, fetcher, userAgents, pageURL)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, fmt.Errorf("no feed links found on %s", pageURL)
+	}
+
+	found := make([]DiscoveredFeed, 0, len(links))
+	for _, link := range links {
+		header := siteName
+		if header == "" {
+			header = link.title
+		}
+
+		cfg := feeds.FeedConfig{
+			URL:     link.href,
+			Header:  header,
+			Profile: feeds.DefaultProfile,
+		}
+
+		res, err := ParseRSSFeed(ctx, fetcher, userAgents, cfg, typesPkg.FeedValidators{}, nil)
+		found = append(found, DiscoveredFeed{
+			Config:    cfg,
+			Title:     link.title,
+			Type:      link.typ,
+			ItemCount: len(res.Posts),
+			Err:       err,
+		})
+	}
+
+	return found, nil
+}
+
+func fetchFeedLinks(ctx context.Context, fetcher *Fetcher, userAgents typesPkg.Agents, pageURL string) ([]feedLink, string, error) {
+	if err := fetcher.checkRobots(ctx, fetcher.client, pageURL, userAgents.Bot); err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgents.Bot)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	resp, err := FeedRetryPolicy.Do(ctx, fetcher.client, req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	// Relative hrefs resolve against the final URL after redirects
+	return parseFeedLinks(io.LimitReader(resp.Body, maxDiscoveryPageBytes), resp.Request.URL)
+}
+
+func parseFeedLinks(r io.Reader, base *url.URL) ([]feedLink, string, error) {
+	var links []feedLink
+	var siteName, pageTitle string
+	seen := make(map[string]bool)
+
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); !errors.Is(err, io.EOF) {
+				return nil, "", fmt.Errorf("failed to parse HTML: %w", err)
+			}
+			if siteName == "" {
+				siteName = pageTitle
+			}
+			return links, siteName, nil
+
+		case html.TextToken:
+			if inTitle && pageTitle == "" {
+				pageTitle = strings.TrimSpace(string(tokenizer.Text()))
+			}
+
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "title" {
+				inTitle = false
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = tokenizer.TagAttr()
+				attrs[string(key)] = string(val)
+			}
+
+			switch string(name) {
+			case "title":
+				inTitle = true
+			case "base":
+				if href, err := base.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+					base = href
+				}
+			case "meta":
+				if attrs["property"] == "og:site_name" && siteName == "" {
+					siteName = strings.TrimSpace(attrs["content"])
+				}
+			case "link":
+				if !hasToken(attrs["rel"], "alternate") {
+					continue
+				}
+				typ := strings.ToLower(strings.TrimSpace(attrs["type"]))
+				if !feedLinkTypes[typ] || strings.TrimSpace(attrs["href"]) == "" {
+					continue
+				}
+				href, err := base.Parse(strings.TrimSpace(attrs["href"]))
+				if err != nil || seen[href.String()] {
+					continue
+				}
+				seen[href.String()] = true
+				links = append(links, feedLink{
+					href:  href.String(),
+					title: strings.TrimSpace(attrs["title"]),
+					typ:   typ,
+				})
+			}
+		}
+	}
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(strings.ToLower(list)) {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,446 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	_ "github.com/emersion/go-message/charset" // non-UTF-8 newsletters
+	"github.com/emersion/go-message/mail"
+)
+
+const (
+	sourceEmail = "email"
+
+	maxEmailMessages  = 50      // newest messages read per run
+	maxEmailBodyBytes = 2 << 20 // per message
+	imapTimeout       = 30 * time.Second
+)
+
+var (
+	// Boilerplate every newsletter carries
+	emailBoilerplateRe = regexp.MustCompile(`(?i)unsubscribe|manage.?(your.?)?(preferences|subscription)|view.?(it.?)?(in|on).?(your.?)?(browser|web)|update.?your.?profile`)
+
+	// mboxrd escapes body lines starting with "From " by prefixing ">"
+	mboxQuotedFromRe = regexp.MustCompile(`^>+From `)
+)
+
+type emailRule struct {
+	from   string
+	header string
+	scrape feeds.ScrapeRules
+	sels   scrapeSelectors
+	skip   []*regexp.Regexp
+}
+
+type emailMessage struct {
+	id   string
+	from string
+	date time.Time
+	html string
+}
+
+func compileEmailRules(rules []feeds.EmailRule) ([]emailRule, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("email source needs at least one Email rule")
+	}
+
+	compiled := make([]emailRule, 0, len(rules))
+	for _, r := range rules {
+		scrape := r.Scrape
+		if strings.TrimSpace(scrape.Item) == "" {
+			scrape.Item = "a[href]"
+		}
+		sels, err := compileScrapeRules(scrape)
+		if err != nil {
+			return nil, fmt.Errorf("email rule %q: %w", r.From, err)
+		}
+
+		skip := make([]*regexp.Regexp, 0, len(r.Skip))
+		for _, pattern := range r.Skip {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("email rule %q: invalid skip pattern %q: %w", r.From, pattern, err)
+			}
+			skip = append(skip, re)
+		}
+
+		compiled = append(compiled, emailRule{
+			from:   strings.ToLower(strings.TrimSpace(r.From)),
+			header: r.Header,
+			scrape: scrape,
+			sels:   sels,
+			skip:   skip,
+		})
+	}
+	return compiled, nil
+}
+
+func (r emailRule) matches(from string) bool {
+	from = strings.ToLower(from)
+	switch {
+	case r.from == "":
+		return true
+	case strings.HasPrefix(r.from, "@"):
+		return strings.HasSuffix(from, r.from)
+	default:
+		return from == r.from
+	}
+}
+
+func (r emailRule) skips(post typesPkg.MainStruct) bool {
+	if emailBoilerplateRe.MatchString(post.Title) || emailBoilerplateRe.MatchString(post.Link) {
+		return true
+	}
+	for _, re := range r.skip {
+		if re.MatchString(post.Link) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEmailFeed reads the newsletters of the last MaxAge from the feed's
+// mailbox. For IMAP the validators hold the mailbox's UIDVALIDITY and
+// UIDNEXT, so a run without new mail is answered as not modified.
+func parseEmailFeed(ctx context.Context, feed feeds.FeedConfig, validators typesPkg.FeedValidators) (FeedResult, error) {
+	rules, err := compileEmailRules(feed.Email)
+	if err != nil {
+		return FeedResult{}, err
+	}
+
+	// Expanded before parsing so credentials can come from the environment;
+	// the error must not echo them
+	mailbox, err := url.Parse(os.ExpandEnv(feed.URL))
+	if err != nil {
+		return FeedResult{}, errors.New("invalid mailbox URL, percent-encode special characters in credentials")
+	}
+
+	maxAge := feed.MaxAge
+	if maxAge <= 0 {
+		maxAge = feeds.DefaultMaxAge
+	}
+	since := time.Now().Add(-maxAge)
+
+	var raws [][]byte
+	newValidators := typesPkg.FeedValidators{}
+	switch mailbox.Scheme {
+	case "imap", "imaps":
+		var notModified bool
+		raws, newValidators, notModified, err = fetchIMAP(ctx, mailbox, since, validators)
+		if notModified {
+			return FeedResult{Validators: validators, NotModified: true}, nil
+		}
+	case "mbox":
+		raws, err = readMbox(mailbox.Path)
+	case "maildir":
+		raws, err = readMaildir(mailbox.Path)
+	default:
+		return FeedResult{}, fmt.Errorf("unknown mailbox scheme %q", mailbox.Scheme)
+	}
+	if err != nil {
+		return FeedResult{}, err
+	}
+
+	var warnings []string
+	messages := make([]emailMessage, 0, len(raws))
+	unreadable := 0
+	for _, raw := range raws {
+		msg, err := readEmail(raw)
+		if err != nil {
+			unreadable++
+			continue
+		}
+		if !msg.date.IsZero() && msg.date.Before(since) {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	if unreadable > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d messages could not be read", unreadable))
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].date.After(messages[j].date)
+	})
+	if len(messages) > maxEmailMessages {
+		messages = messages[:maxEmailMessages]
+	}
+
+	maxItems := feed.MaxItems
+	if maxItems <= 0 {
+		maxItems = defaultMaxFeedItems
+	}
+
+	var posts []typesPkg.MainStruct
+	for _, msg := range messages {
+		for _, rule := range rules {
+			if rule.matches(msg.from) {
+				posts = append(posts, emailToPosts(msg, rule, feed)...)
+				break
+			}
+		}
+	}
+	if len(posts) > maxItems {
+		warnings = append(warnings, fmt.Sprintf("mailbox has more than %d items, the rest were skipped", maxItems))
+		posts = posts[:maxItems]
+	}
+
+	// A quiet inbox is not an error, newsletters don't arrive every run
+	return FeedResult{Posts: posts, Validators: newValidators, Warnings: warnings}, nil
+}
+
+func readEmail(raw []byte) (emailMessage, error) {
+	mr, err := mail.CreateReader(bytes.NewReader(raw))
+	if err != nil {
+		return emailMessage{}, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	var msg emailMessage
+	if from, err := mr.Header.AddressList("From"); err == nil && len(from) > 0 {
+		msg.from = from[0].Address
+	}
+	msg.date, _ = mr.Header.Date()
+	msg.id, _ = mr.Header.MessageID()
+	if msg.id == "" {
+		subject, _ := mr.Header.Subject()
+		msg.id = msg.from + "|" + msg.date.UTC().Format(time.RFC3339) + "|" + subject
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break // io.EOF, or a broken part after the ones we could read
+		}
+		h, ok := part.Header.(*mail.InlineHeader)
+		if !ok {
+			continue
+		}
+		if ct, _, _ := h.ContentType(); ct != "text/html" {
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(part.Body, maxEmailBodyBytes))
+		if err == nil {
+			msg.html = string(body)
+			break
+		}
+	}
+	return msg, nil
+}
+
+// emailToPosts scrapes one message with its sender's rule. GUIDs hash the
+// Message-ID with the link, so the same message always yields the same keys.
+func emailToPosts(msg emailMessage, rule emailRule, feed feeds.FeedConfig) []typesPkg.MainStruct {
+	if msg.html == "" {
+		return nil // plain-text only
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(msg.html))
+	if err != nil {
+		return nil
+	}
+
+	fc := feed
+	fc.Scrape = rule.scrape
+	if rule.header != "" {
+		fc.Header = rule.header
+	}
+
+	h := strings.ReplaceAll(feed.Header, " ", "")
+
+	scraped := scrapeDocument(doc, rule.sels, documentBase(doc, &url.URL{}), fc)
+	posts := make([]typesPkg.MainStruct, 0, len(scraped))
+	for _, post := range scraped {
+		if rule.skips(post) {
+			continue
+		}
+		sum := sha256.Sum256([]byte(msg.id + "|" + post.Link))
+		post.GUID = h + ":" + hex.EncodeToString(sum[:16])
+		if post.Published.IsZero() {
+			post.Published = msg.date
+		}
+		posts = append(posts, post)
+	}
+	return posts
+}
+
+func fetchIMAP(ctx context.Context, mailbox *url.URL, since time.Time, validators typesPkg.FeedValidators) ([][]byte, typesPkg.FeedValidators, bool, error) {
+	host := mailbox.Hostname()
+	port := mailbox.Port()
+	if port == "" {
+		port = "993"
+		if mailbox.Scheme == "imap" {
+			port = "143"
+		}
+	}
+	addr := net.JoinHostPort(host, port)
+	tlsConfig := &tls.Config{ServerName: host}
+	dialer := &net.Dialer{Timeout: imapTimeout}
+
+	var c *client.Client
+	var err error
+	if mailbox.Scheme == "imaps" {
+		c, err = client.DialWithDialerTLS(dialer, addr, tlsConfig)
+	} else {
+		c, err = client.DialWithDialer(dialer, addr)
+	}
+	if err != nil {
+		return nil, validators, false, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer c.Logout()
+
+	c.Timeout = imapTimeout
+	stop := context.AfterFunc(ctx, func() { _ = c.Terminate() })
+	defer stop()
+
+	if mailbox.Scheme == "imap" {
+		if ok, _ := c.SupportStartTLS(); !ok {
+			return nil, validators, false, fmt.Errorf("%s does not offer STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return nil, validators, false, fmt.Errorf("STARTTLS with %s failed: %w", addr, err)
+		}
+	}
+
+	password, _ := mailbox.User.Password()
+	if err := c.Login(mailbox.User.Username(), password); err != nil {
+		return nil, validators, false, fmt.Errorf("IMAP login failed: %w", err)
+	}
+
+	folder := strings.TrimPrefix(mailbox.Path, "/")
+	if folder == "" {
+		folder = "INBOX"
+	}
+	status, err := c.Select(folder, true)
+	if err != nil {
+		return nil, validators, false, fmt.Errorf("failed to select %s: %w", folder, err)
+	}
+
+	newValidators := typesPkg.FeedValidators{ETag: fmt.Sprintf("imap:%d:%d", status.UidValidity, status.UidNext)}
+	if newValidators == validators {
+		return nil, validators, true, nil
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.Since = since
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return nil, validators, false, fmt.Errorf("IMAP search failed: %w", err)
+	}
+	if len(uids) == 0 {
+		return nil, newValidators, false, nil
+	}
+
+	// UIDs grow with arrival, the newest messages are the highest
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	if len(uids) > maxEmailMessages {
+		uids = uids[len(uids)-maxEmailMessages:]
+	}
+
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uids...)
+	section := &imap.BodySectionName{Peek: true}
+
+	messages := make(chan *imap.Message, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.UidFetch(seqset, []imap.FetchItem{section.FetchItem()}, messages)
+	}()
+
+	var raws [][]byte
+	for msg := range messages {
+		body := msg.GetBody(section)
+		if body == nil {
+			continue
+		}
+		if raw, err := io.ReadAll(io.LimitReader(body, maxEmailBodyBytes)); err == nil {
+			raws = append(raws, raw)
+		}
+	}
+	if err := <-done; err != nil {
+		return nil, validators, false, fmt.Errorf("IMAP fetch failed: %w", err)
+	}
+
+	return raws, newValidators, false, nil
+}
+
+// readMbox splits an mbox file on its "From " separator lines.
+func readMbox(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mbox: %w", err)
+	}
+
+	var raws [][]byte
+	var current []byte
+	started := false
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("From ")) {
+			if started {
+				raws = append(raws, current)
+			}
+			current, started = nil, true
+			continue
+		}
+		if !started {
+			continue
+		}
+		if mboxQuotedFromRe.Match(line) {
+			line = line[1:]
+		}
+		current = append(current, line...)
+	}
+	if started {
+		raws = append(raws, current)
+	}
+	return raws, nil
+}
+
+// readMaildir returns every message in the Maildir's new/ and cur/.
+func readMaildir(dir string) ([][]byte, error) {
+	var raws [][]byte
+	found := false
+	for _, sub := range []string{"new", "cur"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Maildir: %w", err)
+		}
+		found = true
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			raw, err := os.ReadFile(filepath.Join(dir, sub, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read Maildir message: %w", err)
+			}
+			raws = append(raws, raw)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not a Maildir (no new/ or cur/)", dir)
+	}
+	return raws, nil
+}
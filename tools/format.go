@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+type feedFormat int
+
+const (
+	formatUnknown feedFormat = iota
+	formatRSS
+	formatRDF
+	formatAtom
+	formatJSON
+	formatSitemap
+)
+
+func (f feedFormat) String() string {
+	switch f {
+	case formatRSS:
+		return "rss"
+	case formatRDF:
+		return "rdf"
+	case formatAtom:
+		return "atom"
+	case formatJSON:
+		return "json"
+	case formatSitemap:
+		return "sitemap"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	nsAtom   = "http://www.w3.org/2005/Atom"
+	nsAtom03 = "http://purl.org/atom/ns#"
+	nsRDF    = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+	nsSitemap = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// resolveFormat maps an explicit FeedConfig.Format; formatUnknown means the
+// format has to be sniffed from the document.
+func resolveFormat(configured string) (feedFormat, error) {
+	switch configured {
+	case "":
+		return formatUnknown, nil
+	case "rss":
+		return formatRSS, nil
+	case "rdf":
+		return formatRDF, nil
+	case "atom":
+		return formatAtom, nil
+	case "json":
+		return formatJSON, nil
+	case "sitemap":
+		return formatSitemap, nil
+	default:
+		return formatUnknown, fmt.Errorf("unknown feed format %q", configured)
+	}
+}
+
+// formatFromRoot decides the XML dialect from the root element and its
+// namespace.
+func formatFromRoot(start xml.StartElement) (feedFormat, error) {
+	switch {
+	case start.Name.Local == "rss":
+		return formatRSS, nil
+	case start.Name.Local == "RDF" && start.Name.Space == nsRDF:
+		return formatRDF, nil
+	case start.Name.Local == "feed" && (start.Name.Space == nsAtom || start.Name.Space == nsAtom03 || start.Name.Space == ""):
+		return formatAtom, nil
+	case start.Name.Local == "urlset" && start.Name.Space == nsSitemap:
+		return formatSitemap, nil
+	case start.Name.Local == "sitemapindex" && start.Name.Space == nsSitemap:
+		return formatUnknown, errors.New("sitemap index, point the feed at one of its news sitemaps")
+	default:
+		return formatUnknown, errors.New("unrecognised root element <" + start.Name.Local + ">")
+	}
+}
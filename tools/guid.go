@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+// Every key carries this namespace, so a future change to how keys are
+// built can't collide with stored records. Records from before keys were
+// versioned are under the parser's bare GUID, which ApplyGUIDStrategy keeps
+// among the AltGUIDs.
+const guidVersion = "v2"
+
+const (
+	guidFeed  = "feed"  // the item's own GUID / id, as built by the parser
+	guidLink  = "link"  // the canonical link
+	guidTitle = "title" // hash of the normalised title and the link's host
+)
+
+// ParseGUIDStrategy splits a FeedConfig.GUIDStrategy such as "link" or
+// "feed+link" into its parts, primary key first.
+func ParseGUIDStrategy(strategy string) ([]string, error) {
+	if strategy == "" {
+		return []string{guidFeed}, nil
+	}
+
+	parts := strings.Split(strategy, "+")
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		switch part {
+		case guidFeed, guidLink, guidTitle:
+		default:
+			return nil, fmt.Errorf("unknown GUID strategy %q in %q", part, strategy)
+		}
+		if seen[part] {
+			return nil, fmt.Errorf("GUID strategy %q repeats %q", strategy, part)
+		}
+		seen[part] = true
+	}
+	return parts, nil
+}
+
+func feedKey(guid string) string {
+	if guid == "" {
+		return ""
+	}
+	return guidVersion + ":feed:" + guid
+}
+
+func linkKey(link string) string {
+	if link == "" {
+		return ""
+	}
+	return guidVersion + ":link:" + link
+}
+
+func titleKey(title, link string) string {
+	if title == "" {
+		return ""
+	}
+
+	var host string
+	if u, err := url.Parse(link); err == nil {
+		host = strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	}
+
+	sum := sha256.Sum256([]byte(strings.ToLower(title) + "|" + host))
+	return guidVersion + ":title:" + hex.EncodeToString(sum[:16])
+}
+
+// GUIDKeys returns the dedup keys of a post under strategy, primary first.
+// The post's GUID is taken as the feed key, so it must not have been
+// replaced by ApplyGUIDStrategy already.
+func GUIDKeys(post typesPkg.MainStruct, strategy []string) []string {
+	keys := make([]string, 0, len(strategy))
+	for _, part := range strategy {
+		var key string
+		switch part {
+		case guidFeed:
+			key = feedKey(post.GUID)
+		case guidLink:
+			key = linkKey(post.Link)
+		case guidTitle:
+			key = titleKey(post.Title, post.Link)
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ApplyGUIDStrategy re-keys posts according to the feed's GUID strategy:
+// the first key becomes the GUID and the others AltGUIDs. The feed key and
+// the bare parser GUID are always kept as AltGUIDs as well, so records
+// written before keys were versioned, or under the default strategy before
+// a feed switched to another one, still match without a migration. Posts
+// without any usable key are dropped. It expects normalised titles and
+// cleaned links.
+func ApplyGUIDStrategy(posts []typesPkg.MainStruct, feed feeds.FeedConfig) ([]typesPkg.MainStruct, error) {
+	strategy, err := ParseGUIDStrategy(feed.GUIDStrategy)
+	if err != nil {
+		return nil, err
+	}
+	withFeed := false
+	for _, part := range strategy {
+		withFeed = withFeed || part == guidFeed
+	}
+
+	kept := posts[:0]
+	for _, post := range posts {
+		keys := GUIDKeys(post, strategy)
+		if len(keys) == 0 {
+			continue
+		}
+		alt := append([]string{}, keys[1:]...)
+		if post.GUID != "" {
+			if !withFeed {
+				alt = append(alt, feedKey(post.GUID))
+			}
+			alt = append(alt, post.GUID)
+		}
+		post.GUID = keys[0]
+		post.AltGUIDs = append(alt, post.AltGUIDs...)
+		kept = append(kept, post)
+	}
+	return kept, nil
+}
+
+// GUIDForLink is the GUID a post gets once its link is replaced by link,
+// e.g. after redirects were resolved.
+func GUIDForLink(post typesPkg.MainStruct, feed feeds.FeedConfig, link string) string {
+	strategy, err := ParseGUIDStrategy(feed.GUIDStrategy)
+	if err != nil {
+		return post.GUID
+	}
+
+	switch strategy[0] {
+	case guidLink:
+		return linkKey(link)
+	case guidTitle:
+		return titleKey(post.Title, link)
+	default:
+		// Feed GUIDs only follow the link when they were the link
+		if post.GUID == feedKey(post.Link) {
+			return feedKey(link)
+		}
+		return post.GUID
+	}
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+func TestApplyGUIDStrategyKeepsLegacyKeys(t *testing.T) {
+	raw := "https://example.com/story?utm_source=rss"
+	posts := CleanLinks([]typesPkg.MainStruct{{GUID: raw, Link: raw, Title: "Story"}})
+
+	keyed, err := ApplyGUIDStrategy(posts, feeds.FeedConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clean := "https://example.com/story"
+	want := typesPkg.MainStruct{
+		GUID:     "v2:feed:" + clean,
+		Link:     clean,
+		Title:    "Story",
+		AltGUIDs: []string{clean, raw},
+	}
+	if !reflect.DeepEqual(keyed[0], want) {
+		t.Fatalf("got %+v, want %+v", keyed[0], want)
+	}
+
+	if got := GUIDForLink(keyed[0], feeds.FeedConfig{}, "https://example.com/final"); got != "v2:feed:https://example.com/final" {
+		t.Errorf("GUIDForLink = %q", got)
+	}
+}
+
+func TestApplyGUIDStrategyLink(t *testing.T) {
+	posts := []typesPkg.MainStruct{{GUID: "Feed:123", Link: "https://example.com/a", Title: "A"}}
+	keyed, err := ApplyGUIDStrategy(posts, feeds.FeedConfig{GUIDStrategy: "link+feed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"v2:feed:Feed:123", "Feed:123"}
+	if keyed[0].GUID != "v2:link:https://example.com/a" || !reflect.DeepEqual(keyed[0].AltGUIDs, want) {
+		t.Fatalf("got %q %q", keyed[0].GUID, keyed[0].AltGUIDs)
+	}
+
+	// Switching a feed from the default strategy keeps its old keys for lookups.
+	keyed, err = ApplyGUIDStrategy([]typesPkg.MainStruct{{GUID: "Feed:123", Link: "https://example.com/a", Title: "A"}}, feeds.FeedConfig{GUIDStrategy: "link"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyed[0].GUID != "v2:link:https://example.com/a" || !reflect.DeepEqual(keyed[0].AltGUIDs, want) {
+		t.Fatalf("link only: got %q %q", keyed[0].GUID, keyed[0].AltGUIDs)
+	}
+
+	if _, err := ApplyGUIDStrategy(posts, feeds.FeedConfig{GUIDStrategy: "url"}); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+}
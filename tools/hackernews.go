@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+const (
+	sourceHN = "hn"
+
+	hnAPIHost     = "hacker-news.firebaseio.com"
+	hnItemURL     = "https://news.ycombinator.com/item?id="
+	hnAPIMaxItems = 30 // every story is its own request
+)
+
+var (
+	hnPointsRe      = regexp.MustCompile(`Points:\s*(\d+)`)
+	hnCommentsRe    = regexp.MustCompile(`#\s*Comments:\s*(\d+)`)
+	hnCommentsURLRe = regexp.MustCompile(`Comments URL:\s*(\S+)`)
+)
+
+// Item as served by the official API (/v0/item/<id>.json)
+type hnAPIItem struct {
+	ID          int64  `json:"id"`
+	Type        string `json:"type"`
+	By          string `json:"by"`
+	Time        int64  `json:"time"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Text        string `json:"text"`
+	Score       int    `json:"score"`
+	Descendants int    `json:"descendants"`
+	Dead        bool   `json:"dead"`
+	Deleted     bool   `json:"deleted"`
+}
+
+func isHNAPI(req *http.Request) bool {
+	return req.URL.Hostname() == hnAPIHost
+}
+
+// withHNStats reads points, comment count and the discussion link from an
+// hnrss.org item, whose description looks like
+// "Article URL: ... Comments URL: ... Points: 12 # Comments: 3".
+func withHNStats(post typesPkg.MainStruct, item Item) typesPkg.MainStruct {
+	text := plainText(item.Description)
+
+	if m := hnPointsRe.FindStringSubmatch(text); m != nil {
+		post.Score, _ = strconv.Atoi(m[1])
+	}
+	if m := hnCommentsRe.FindStringSubmatch(text); m != nil {
+		post.Comments, _ = strconv.Atoi(m[1])
+	}
+
+	discussion := strings.TrimSpace(item.Comments)
+	if discussion == "" {
+		if m := hnCommentsURLRe.FindStringSubmatch(text); m != nil {
+			discussion = m[1]
+		}
+	}
+	if discussion != post.Link {
+		post.DiscussionLink = discussion
+	}
+
+	// The description is only the stats above
+	post.Description = ""
+	return post
+}
+
+// fetchHNAPIStories reads a story ID list (topstories.json, beststories.json,
+// ...) from body and fetches the stories one by one with the headers of
+// listReq.
+func fetchHNAPIStories(ctx context.Context, client *http.Client, listReq *http.Request, body io.Reader, feed feeds.FeedConfig, maxItems int) ([]typesPkg.MainStruct, bool, error) {
+	var ids []int64
+	if err := json.NewDecoder(body).Decode(&ids); err != nil {
+		return nil, false, fmt.Errorf("failed to parse HN story list: %w", err)
+	}
+
+	if feed.MaxItems <= 0 {
+		maxItems = hnAPIMaxItems
+	}
+	capped := len(ids) > maxItems
+	if capped {
+		ids = ids[:maxItems]
+	}
+
+	posts := make([]typesPkg.MainStruct, 0, len(ids))
+	for _, id := range ids {
+		item, err := fetchHNAPIItem(ctx, client, listReq, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, false, err
+			}
+			continue // one missing story shouldn't cost the whole list
+		}
+		if post, ok := hnAPIItemToPost(item, feed); ok {
+			posts = append(posts, post)
+		}
+	}
+
+	return posts, capped, nil
+}
+
+func fetchHNAPIItem(ctx context.Context, client *http.Client, listReq *http.Request, id int64) (hnAPIItem, error) {
+	req := listReq.Clone(ctx)
+	req.URL.Path = fmt.Sprintf("/v0/item/%d.json", id)
+	req.URL.RawQuery = ""
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+
+	resp, err := FeedRetryPolicy.Do(ctx, client, req)
+	if err != nil {
+		return hnAPIItem{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return hnAPIItem{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var item hnAPIItem
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&item); err != nil {
+		return hnAPIItem{}, fmt.Errorf("failed to parse HN item %d: %w", id, err)
+	}
+	return item, nil
+}
+
+func hnAPIItemToPost(item hnAPIItem, feed feeds.FeedConfig) (typesPkg.MainStruct, bool) {
+	title := strings.TrimSpace(item.Title)
+	if item.ID == 0 || title == "" || item.Dead || item.Deleted || item.Type != "story" {
+		return typesPkg.MainStruct{}, false
+	}
+
+	discussion := hnItemURL + strconv.FormatInt(item.ID, 10)
+	link := strings.TrimSpace(item.URL)
+	if link == "" {
+		// Ask HN and friends: the discussion is the article
+		link = discussion
+		discussion = ""
+	}
+
+	var published time.Time
+	if item.Time > 0 {
+		published = time.Unix(item.Time, 0).UTC()
+	}
+
+	h := strings.ReplaceAll(feed.Header, " ", "")
+
+	return typesPkg.MainStruct{
+		GUID:           h + ":" + hnItemURL + strconv.FormatInt(item.ID, 10), // same GUID hnrss.org gives
+		Title:          title,
+		Header:         feed.Header,
+		Link:           link,
+		Published:      published,
+		Description:    item.Text,
+		Author:         item.By,
+		DiscussionLink: discussion,
+		Score:          item.Score,
+		Comments:       item.Descendants,
+	}, true
+}
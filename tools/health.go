@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"time"
+
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+const (
+	breakerThreshold    = 3 // consecutive failures before a feed is skipped
+	breakerBaseCooldown = 30 * time.Minute
+	breakerMaxCooldown  = 24 * time.Hour
+	maxLastErrorLen     = 500
+)
+
+// CircuitOpen reports whether a failing feed is still cooling down. Once
+// OpenUntil has passed the next run probes the feed again.
+func CircuitOpen(h typesPkg.FeedHealth, now time.Time) bool {
+	return !h.OpenUntil.IsZero() && now.Before(h.OpenUntil)
+}
+
+func RecordSuccess(h typesPkg.FeedHealth, now time.Time, itemCount int) typesPkg.FeedHealth {
+	h.LastAttempt = now
+	h.LastSuccess = now
+	h.ConsecutiveFailures = 0
+	h.LastError = ""
+	h.LastItemCount = itemCount
+	h.OpenUntil = time.Time{}
+	return h
+}
+
+// RecordFailure opens the circuit after breakerThreshold failures in a row,
+// doubling the cooldown with every further failed probe.
+func RecordFailure(h typesPkg.FeedHealth, now time.Time, err error) typesPkg.FeedHealth {
+	h.LastAttempt = now
+	h.ConsecutiveFailures++
+	h.LastError = ensureMaxRunes(err.Error(), maxLastErrorLen)
+
+	if h.ConsecutiveFailures >= breakerThreshold {
+		cooldown := breakerBaseCooldown
+		for i := breakerThreshold; i < h.ConsecutiveFailures && cooldown < breakerMaxCooldown; i++ {
+			cooldown *= 2
+		}
+		h.OpenUntil = now.Add(min(cooldown, breakerMaxCooldown))
+	}
+
+	return h
+}
+
+func ensureMaxRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
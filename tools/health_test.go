@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+func TestRecordFailureCooldown(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	errFetch := errors.New("fetch failed")
+
+	tests := []struct {
+		failures int
+		cooldown time.Duration // zero means the circuit stays closed
+	}{
+		{1, 0},
+		{2, 0},
+		{3, 30 * time.Minute},
+		{4, time.Hour},
+		{5, 2 * time.Hour},
+		{8, 16 * time.Hour},
+		{9, 24 * time.Hour},
+		{20, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		var h typesPkg.FeedHealth
+		for range tt.failures {
+			h = RecordFailure(h, now, errFetch)
+		}
+		if h.ConsecutiveFailures != tt.failures {
+			t.Errorf("%d failures: ConsecutiveFailures = %d", tt.failures, h.ConsecutiveFailures)
+		}
+		if tt.cooldown == 0 {
+			if !h.OpenUntil.IsZero() || CircuitOpen(h, now) {
+				t.Errorf("%d failures: circuit open until %v, want closed", tt.failures, h.OpenUntil)
+			}
+			continue
+		}
+		if got := h.OpenUntil.Sub(now); got != tt.cooldown {
+			t.Errorf("%d failures: cooldown = %v, want %v", tt.failures, got, tt.cooldown)
+		}
+		if !CircuitOpen(h, now.Add(tt.cooldown-time.Second)) {
+			t.Errorf("%d failures: circuit closed before cooldown ended", tt.failures)
+		}
+		if CircuitOpen(h, now.Add(tt.cooldown)) {
+			t.Errorf("%d failures: circuit still open after cooldown", tt.failures)
+		}
+	}
+}
+
+func TestRecordSuccessResets(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var h typesPkg.FeedHealth
+	for range 4 {
+		h = RecordFailure(h, now, errors.New("boom"))
+	}
+	if !CircuitOpen(h, now) {
+		t.Fatal("circuit should be open after 4 failures")
+	}
+
+	later := now.Add(2 * time.Hour)
+	h = RecordSuccess(h, later, 12)
+	if CircuitOpen(h, later) || !h.OpenUntil.IsZero() {
+		t.Errorf("circuit open after success: %v", h.OpenUntil)
+	}
+	if h.ConsecutiveFailures != 0 || h.LastError != "" {
+		t.Errorf("failure state not cleared: %+v", h)
+	}
+	if !h.LastSuccess.Equal(later) || !h.LastAttempt.Equal(later) || h.LastItemCount != 12 {
+		t.Errorf("success not recorded: %+v", h)
+	}
+
+	// A fresh failure after recovery starts counting from one again.
+	h = RecordFailure(h, later, errors.New("boom"))
+	if h.ConsecutiveFailures != 1 || CircuitOpen(h, later) {
+		t.Errorf("after reset: failures = %d, open = %v", h.ConsecutiveFailures, CircuitOpen(h, later))
+	}
+}
+
+func TestRecordFailureTruncatesError(t *testing.T) {
+	long := make([]rune, maxLastErrorLen+50)
+	for i := range long {
+		long[i] = 'é'
+	}
+	h := RecordFailure(typesPkg.FeedHealth{}, time.Now(), errors.New(string(long)))
+	if n := len([]rune(h.LastError)); n != maxLastErrorLen {
+		t.Errorf("LastError has %d runes, want %d", n, maxLastErrorLen)
+	}
+}
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+// JSON Feed 1.0 / 1.1 item (https://www.jsonfeed.org/version/1.1/)
+type JSONFeedItem struct {
+	ID            jsonFeedID       `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	Summary       string           `json:"summary"`
+	ContentText   string           `json:"content_text"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Image         string           `json:"image"`
+	BannerImage   string           `json:"banner_image"`
+	Tags          []string         `json:"tags"`
+	Author        JSONFeedAuthor   `json:"author"`  // 1.0
+	Authors       []JSONFeedAuthor `json:"authors"` // 1.1
+	Attachments   []struct {
+		URL         string `json:"url"`
+		MimeType    string `json:"mime_type"`
+		SizeInBytes int64  `json:"size_in_bytes"`
+	} `json:"attachments"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// Some publishers emit numeric ids even though the spec requires strings.
+type jsonFeedID string
+
+func (id *jsonFeedID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*id = jsonFeedID(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("invalid item id %s", string(b))
+	}
+	*id = jsonFeedID(n.String())
+	return nil
+}
+
+func streamJSONFeed(r io.Reader, feed feeds.FeedConfig, maxItems int, emit func(typesPkg.MainStruct)) (bool, error) {
+	decoder := json.NewDecoder(r)
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return false, fmt.Errorf("failed to parse JSON Feed: %w", err)
+	}
+
+	var version string
+	count := 0
+
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return false, fmt.Errorf("failed to parse JSON Feed: %w", err)
+		}
+		key, _ := tok.(string)
+
+		switch key {
+		case "version":
+			if err := decoder.Decode(&version); err != nil {
+				return false, fmt.Errorf("failed to parse JSON Feed version: %w", err)
+			}
+			if !isJSONFeedVersion(version) {
+				return false, fmt.Errorf("not a JSON Feed document (version %q)", version)
+			}
+		case "items":
+			if err := expectDelim(decoder, '['); err != nil {
+				return false, fmt.Errorf("failed to parse JSON Feed items: %w", err)
+			}
+			for decoder.More() {
+				if count >= maxItems {
+					return true, nil
+				}
+				count++
+
+				var item JSONFeedItem
+				if err := decoder.Decode(&item); err != nil {
+					return false, fmt.Errorf("failed to parse JSON Feed item: %w", err)
+				}
+				if post, ok := jsonItemToPost(item, feed); ok {
+					emit(post)
+				}
+			}
+			if err := expectDelim(decoder, ']'); err != nil {
+				return false, fmt.Errorf("failed to parse JSON Feed items: %w", err)
+			}
+		default:
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return false, fmt.Errorf("failed to parse JSON Feed: %w", err)
+			}
+		}
+	}
+
+	if !isJSONFeedVersion(version) {
+		return false, fmt.Errorf("not a JSON Feed document (version %q)", version)
+	}
+
+	return false, nil
+}
+
+func isJSONFeedVersion(version string) bool {
+	return strings.HasPrefix(version, "https://jsonfeed.org/version/")
+}
+
+func expectDelim(decoder *json.Decoder, want json.Delim) error {
+	tok, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+func jsonItemToPost(item JSONFeedItem, feed feeds.FeedConfig) (typesPkg.MainStruct, bool) {
+	title := strings.TrimSpace(item.Title)
+	link := strings.TrimSpace(item.URL)
+	if link == "" {
+		link = strings.TrimSpace(item.ExternalURL)
+	}
+	if title == "" || link == "" {
+		return typesPkg.MainStruct{}, false
+	}
+
+	h := strings.ReplaceAll(feed.Header, " ", "")
+
+	var guid string
+	if candidate := strings.TrimSpace(string(item.ID)); candidate != "" {
+		if h != "" {
+			guid = h + ":" + candidate
+		} else {
+			guid = candidate
+		}
+	} else {
+		guid = link // fallback — do NOT prefix
+	}
+
+	published, _ := ParseDate(item.DatePublished)
+	updated, _ := ParseDate(item.DateModified)
+
+	description := strings.TrimSpace(item.Summary)
+	if description == "" {
+		description = strings.TrimSpace(item.ContentText)
+	}
+
+	author := strings.TrimSpace(item.Author.Name)
+	if len(item.Authors) > 0 {
+		author = strings.TrimSpace(item.Authors[0].Name)
+	}
+
+	var enclosures []typesPkg.Enclosure
+	for _, a := range item.Attachments {
+		if u := strings.TrimSpace(a.URL); u != "" {
+			enclosures = append(enclosures, typesPkg.Enclosure{URL: u, Type: a.MimeType, Length: a.SizeInBytes})
+		}
+	}
+
+	image := strings.TrimSpace(item.Image)
+	if image == "" {
+		image = strings.TrimSpace(item.BannerImage)
+	}
+
+	return typesPkg.MainStruct{
+		GUID:        guid,
+		Title:       title,
+		Header:      feed.Header,
+		Link:        link,
+		Published:   published,
+		Updated:     updated,
+		Description: description,
+		Author:      author,
+		Categories:  cleanCategories(item.Tags),
+		Enclosures:  enclosures,
+		Image:       image,
+	}, true
+}
@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+// media:thumbnail / media:content (Media RSS)
+type MediaRef struct {
+	URL    string `xml:"url,attr"`
+	Medium string `xml:"medium,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type MediaGroup struct {
+	Thumbnails []MediaRef `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Contents   []MediaRef `xml:"http://search.yahoo.com/mrss/ content"`
+}
+
+// Layouts seen in the wild, tried in order. RSS is supposed to use RFC 822
+// and Atom RFC 3339, but plenty of feeds do neither.
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 02 Jan 2006 15:04 MST",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"Mon, 2 Jan 06 15:04:05 -0700",
+	"Mon, 2 Jan 06 15:04:05 MST",
+	"Mon,2 Jan 2006 15:04:05 -0700",
+	"Mon,2 Jan 2006 15:04:05 MST",
+	"Monday, 02-Jan-06 15:04:05 MST",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Mon, 2 January 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"02 Jan 2006 15:04 -0700",
+	"02 Jan 2006 15:04 MST",
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.ANSIC,
+	time.UnixDate,
+	time.RubyDate,
+}
+
+// Zone abbreviations Go cannot resolve on its own (it would treat them as
+// UTC unless they happen to be the local zone), as offsets in seconds.
+// Abbreviations with more than one meaning (IST, BST, ...) are left out; the
+// US ones are fixed by RFC 822.
+var zoneOffsets = map[string]int{
+	"UT": 0, "UTC": 0, "GMT": 0, "Z": 0,
+	"EST": -5 * 3600, "EDT": -4 * 3600, "CST": -6 * 3600, "CDT": -5 * 3600,
+	"MST": -7 * 3600, "MDT": -6 * 3600, "PST": -8 * 3600, "PDT": -7 * 3600,
+	"CET": 1 * 3600, "CEST": 2 * 3600, "EET": 2 * 3600, "EEST": 3 * 3600,
+	"JST": 9 * 3600, "KST": 9 * 3600,
+	"AEST": 10 * 3600, "AEDT": 11 * 3600, "ACST": 9*3600 + 1800, "ACDT": 10*3600 + 1800,
+	"NZST": 12 * 3600, "NZDT": 13 * 3600,
+}
+
+// ParseDate normalises a feed date to UTC. The bool is false (and the time
+// zero) when none of the known layouts match.
+func ParseDate(raw string) (time.Time, bool) {
+	s := strings.Join(strings.Fields(raw), " ")
+	if s == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+
+		if name, offset := t.Zone(); offset == 0 {
+			if seconds, ok := zoneOffsets[strings.ToUpper(name)]; ok {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(),
+					time.FixedZone(name, seconds))
+			}
+		}
+		return t.UTC(), true
+	}
+
+	return time.Time{}, false
+}
+
+// atomLinks picks the entry's alternate link (rel="alternate" or no rel) and
+// collects rel="enclosure" links.
+func atomLinks(links []AtomLink) (string, []typesPkg.Enclosure) {
+	var link, fallback string
+	var enclosures []typesPkg.Enclosure
+
+	for _, l := range links {
+		href := strings.TrimSpace(l.Href)
+		if href == "" {
+			continue
+		}
+		switch l.Rel {
+		case "", "alternate":
+			if link == "" {
+				link = href
+			}
+		case "enclosure":
+			enclosures = append(enclosures, typesPkg.Enclosure{URL: href, Type: l.Type, Length: parseLength(l.Length)})
+		default:
+			if fallback == "" && l.Rel != "self" && l.Rel != "replies" {
+				fallback = href
+			}
+		}
+	}
+
+	if link == "" {
+		link = fallback
+	}
+	return link, enclosures
+}
+
+func isImageRef(m MediaRef) bool {
+	return m.Medium == "image" || strings.HasPrefix(m.Type, "image/") || (m.Medium == "" && m.Type == "")
+}
+
+// pickImage prefers an explicit thumbnail, then image media content, then
+// image enclosures.
+func pickImage(thumbnails, contents []MediaRef, group MediaGroup, enclosures []typesPkg.Enclosure) string {
+	for _, list := range [][]MediaRef{thumbnails, group.Thumbnails} {
+		for _, m := range list {
+			if u := strings.TrimSpace(m.URL); u != "" {
+				return u
+			}
+		}
+	}
+	for _, list := range [][]MediaRef{contents, group.Contents} {
+		for _, m := range list {
+			if u := strings.TrimSpace(m.URL); u != "" && isImageRef(m) {
+				return u
+			}
+		}
+	}
+	for _, e := range enclosures {
+		if strings.HasPrefix(e.Type, "image/") {
+			return e.URL
+		}
+	}
+	return ""
+}
+
+func cleanCategories(raw []string) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(raw))
+	out := make([]string, 0, len(raw))
+	for _, c := range raw {
+		c = strings.TrimSpace(c)
+		key := strings.ToLower(c)
+		if c == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+// parseLength reads an enclosure length attribute; anything that isn't a
+// plain byte count is unknown (0) rather than a reason to drop the item.
+func parseLength(raw string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 EST", time.Date(2006, 1, 2, 20, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 CEST", time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 ACST", time.Date(2006, 1, 2, 5, 34, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 +0530", time.Date(2006, 1, 2, 9, 34, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05+05:30", time.Date(2006, 1, 2, 9, 34, 5, 0, time.UTC)},
+		{"2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, ok := ParseDate(tt.in)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, %v, want %v", tt.in, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := ParseDate("yesterday"); ok {
+		t.Error(`ParseDate("yesterday") succeeded`)
+	}
+}
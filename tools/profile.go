@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+const defaultFeedAccept = "application/rss+xml, application/atom+xml, application/feed+json, application/xml, text/xml, application/json, */*"
+
+func resolveProfile(feed feeds.FeedConfig) (feeds.FetchProfile, error) {
+	name := feed.Profile
+	if name == "" {
+		name = feeds.DefaultProfile
+	}
+
+	profile, ok := feeds.Profiles[name]
+	if !ok {
+		return feeds.FetchProfile{}, fmt.Errorf("unknown fetch profile %q", name)
+	}
+	return profile, nil
+}
+
+// profileUserAgent resolves the profile's user agent, which may name one of
+// userAgents.
+func profileUserAgent(profile feeds.FetchProfile, userAgents typesPkg.Agents) string {
+	namedAgents := map[string]string{
+		"bot":    userAgents.Bot,
+		"chrome": userAgents.Chrome,
+		"reader": userAgents.Reader,
+	}
+
+	userAgent, ok := namedAgents[profile.UserAgent]
+	if !ok {
+		userAgent = profile.UserAgent
+	}
+	if userAgent == "" {
+		userAgent = userAgents.Bot
+	}
+	return userAgent
+}
+
+// applyProfile sets the profile's user agent, headers, cookies, query
+// parameters and credentials on req. Only for requests to the feed itself,
+// the credentials belong to its site.
+func applyProfile(req *http.Request, profile feeds.FetchProfile, userAgents typesPkg.Agents) {
+	accept := profile.Accept
+	if accept == "" {
+		accept = defaultFeedAccept
+	}
+
+	req.Header.Set("User-Agent", profileUserAgent(profile, userAgents))
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	if profile.Referer != "" {
+		req.Header.Set("Referer", profile.Referer)
+	}
+
+	for name, value := range profile.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	if len(profile.Cookies) > 0 {
+		names := make([]string, 0, len(profile.Cookies))
+		for name := range profile.Cookies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		pairs := make([]string, 0, len(names))
+		for _, name := range names {
+			cookie := &http.Cookie{Name: name, Value: os.ExpandEnv(profile.Cookies[name])}
+			pairs = append(pairs, cookie.String())
+		}
+		req.Header.Set("Cookie", strings.Join(pairs, "; "))
+	}
+
+	if len(profile.Query) > 0 {
+		q := req.URL.Query()
+		for name, value := range profile.Query {
+			q.Set(name, os.ExpandEnv(value))
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	if profile.BearerToken != "" {
+		if token := os.ExpandEnv(profile.BearerToken); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	sourceReddit = "reddit"
+	redditBase   = "https://www.reddit.com"
+)
+
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Kind string     `json:"kind"`
+			Data redditPost `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+type redditPost struct {
+	ID                  string  `json:"id"`
+	Name                string  `json:"name"` // "t3_" + ID, same as the Atom entry id
+	Title               string  `json:"title"`
+	URL                 string  `json:"url"`
+	URLOverriddenByDest string  `json:"url_overridden_by_dest"`
+	Permalink           string  `json:"permalink"`
+	Score               int     `json:"score"`
+	NumComments         int     `json:"num_comments"`
+	CreatedUTC          float64 `json:"created_utc"`
+	IsSelf              bool    `json:"is_self"`
+	Stickied            bool    `json:"stickied"`
+	Author              string  `json:"author"`
+	LinkFlairText       string  `json:"link_flair_text"`
+	Selftext            string  `json:"selftext"`
+	Thumbnail           string  `json:"thumbnail"`
+	Preview             struct {
+		Images []struct {
+			Source struct {
+				URL string `json:"url"`
+			} `json:"source"`
+		} `json:"images"`
+	} `json:"preview"`
+}
+
+func redditListingURL(feedURL string) (string, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid reddit feed URL: %w", err)
+	}
+
+	p := strings.TrimSuffix(u.Path, "/")
+	p = strings.TrimSuffix(p, ".rss")
+	p = strings.TrimSuffix(p, ".json")
+	p = strings.TrimSuffix(p, "/")
+	u.Path = p + "/.json"
+
+	// raw_json=1 stops reddit from HTML-escaping the strings
+	q := u.Query()
+	q.Set("raw_json", "1")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+func redditAbsolute(link string) string {
+	link = strings.TrimSpace(link)
+	if strings.HasPrefix(link, "/") {
+		return redditBase + link
+	}
+	return link
+}
+
+func streamRedditListing(r io.Reader, feed feeds.FeedConfig, maxItems int, emit func(typesPkg.MainStruct)) (bool, error) {
+	var listing redditListing
+	if err := json.NewDecoder(r).Decode(&listing); err != nil {
+		return false, fmt.Errorf("failed to parse reddit listing: %w", err)
+	}
+
+	count := 0
+	for _, child := range listing.Data.Children {
+		if child.Kind != "t3" {
+			continue
+		}
+		if count >= maxItems {
+			return true, nil
+		}
+		count++
+
+		if post, ok := redditPostToPost(child.Data, feed); ok {
+			emit(post)
+		}
+	}
+	return false, nil
+}
+
+func redditPostToPost(p redditPost, feed feeds.FeedConfig) (typesPkg.MainStruct, bool) {
+	title := strings.TrimSpace(p.Title)
+	if title == "" || p.Stickied {
+		return typesPkg.MainStruct{}, false
+	}
+
+	name := p.Name
+	if name == "" && p.ID != "" {
+		name = "t3_" + p.ID
+	}
+	if name == "" {
+		return typesPkg.MainStruct{}, false
+	}
+
+	discussion := redditAbsolute(p.Permalink)
+
+	link := redditAbsolute(p.URLOverriddenByDest)
+	if link == "" {
+		link = redditAbsolute(p.URL)
+	}
+	if link == "" {
+		link = discussion
+	}
+
+	// A self post has nothing to read but the discussion itself
+	if p.IsSelf || link == discussion {
+		discussion = ""
+	}
+
+	var published time.Time
+	if p.CreatedUTC > 0 {
+		published = time.Unix(int64(p.CreatedUTC), 0).UTC()
+	}
+
+	var image string
+	if len(p.Preview.Images) > 0 {
+		image = p.Preview.Images[0].Source.URL
+	} else if strings.HasPrefix(p.Thumbnail, "http") {
+		image = p.Thumbnail
+	}
+
+	var categories []string
+	if p.LinkFlairText != "" {
+		categories = []string{p.LinkFlairText}
+	}
+
+	h := strings.ReplaceAll(feed.Header, " ", "")
+
+	return typesPkg.MainStruct{
+		GUID:           h + ":" + name,
+		Title:          title,
+		Header:         feed.Header,
+		Link:           link,
+		Published:      published,
+		Description:    strings.TrimSpace(p.Selftext),
+		Author:         p.Author,
+		Categories:     cleanCategories(categories),
+		Image:          image,
+		DiscussionLink: discussion,
+		Score:          p.Score,
+		Comments:       p.NumComments,
+	}, true
+}
+
+// withRedditAtomLinks swaps the post's link for the "[link]" anchor in
+// content and keeps the old one as the discussion.
+func withRedditAtomLinks(post typesPkg.MainStruct, content string) typesPkg.MainStruct {
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+
+	var href string
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return post
+
+		case html.StartTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if string(name) != "a" {
+				continue
+			}
+			href = ""
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = tokenizer.TagAttr()
+				if string(key) == "href" {
+					href = string(val)
+				}
+			}
+
+		case html.TextToken:
+			if href == "" || strings.TrimSpace(string(tokenizer.Text())) != "[link]" {
+				continue
+			}
+			link := redditAbsolute(href)
+			if link != post.Link {
+				post.DiscussionLink = post.Link
+				post.Link = link
+			}
+			return post
+
+		case html.EndTagToken:
+			href = ""
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy is shared by the feed fetcher and the Telegram client.
+type RetryPolicy struct {
+	MaxAttempts   int
+	BaseBackoff   time.Duration
+	MaxBackoff    time.Duration
+	MaxRetryAfter time.Duration // Retry-After values above this are not worth waiting for
+	JitterPct     int           // +/- jitter applied to the exponential backoff
+}
+
+var FeedRetryPolicy = RetryPolicy{
+	MaxAttempts:   3,
+	BaseBackoff:   500 * time.Millisecond,
+	MaxBackoff:    8 * time.Second,
+	MaxRetryAfter: 30 * time.Second,
+	JitterPct:     20,
+}
+
+// Retryable reports whether a response status is worth another attempt:
+// rate limiting and server-side failures are, other 4xx never succeed.
+func (p RetryPolicy) Retryable(status int) bool {
+	return status == http.StatusTooManyRequests ||
+		status == http.StatusRequestTimeout ||
+		(status >= 500 && status <= 599)
+}
+
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := min(p.BaseBackoff<<(attempt-1), p.MaxBackoff)
+
+	jitter := int64(delay) * int64(p.JitterPct) / 100
+	if jitter <= 0 {
+		return delay
+	}
+
+	offset := rand.Int63n(2*jitter+1) - jitter
+	return time.Duration(int64(delay) + offset)
+}
+
+// Delay prefers an explicit server hint over the exponential backoff. The
+// bool is false when the server asks us to wait longer than MaxRetryAfter.
+func (p RetryPolicy) Delay(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter <= 0 {
+		return p.Backoff(attempt), true
+	}
+	if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+		return retryAfter, false
+	}
+	return retryAfter, true
+}
+
+// ParseRetryAfter understands both forms of the Retry-After header
+// (delta-seconds and HTTP-date). It returns 0 when absent or invalid.
+func ParseRetryAfter(h http.Header) time.Duration {
+	ra := strings.TrimSpace(h.Get("Retry-After"))
+	if ra == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(ra); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(ra); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// SleepContext waits for d unless ctx is cancelled first. It fails straight
+// away if ctx's deadline would pass before d has elapsed.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return fmt.Errorf("waiting %v would exceed the deadline: %w", d, context.DeadlineExceeded)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// redactURL drops the query and credentials from u: profiles put tokens in
+// the query, and errors mentioning the URL end up in logs and FeedHealth.
+func redactURL(u *url.URL) string {
+	r := *u
+	r.User = nil
+	r.RawQuery = ""
+	r.ForceQuery = false
+	r.Fragment = ""
+	return r.String()
+}
+
+// Do sends req until it gets a non-retryable response or runs out of
+// attempts. The last response is returned as-is so the caller can report its
+// status; req must not carry a body. Errors never include req's query.
+func (p RetryPolicy) Do(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	var lastErr error
+	target := redactURL(req.URL)
+
+	for attempt := 1; attempt <= p.MaxAttempts; attempt++ {
+		resp, err := client.Do(req)
+
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = target
+		}
+
+		var retryAfter time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			lastErr = err
+		case p.Retryable(resp.StatusCode) && attempt < p.MaxAttempts:
+			retryAfter = ParseRetryAfter(resp.Header)
+			lastErr = fmt.Errorf("status %d", resp.StatusCode)
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		if attempt == p.MaxAttempts {
+			break
+		}
+
+		wait, ok := p.Delay(attempt, retryAfter)
+		if !ok {
+			return nil, fmt.Errorf("%s asked to retry after %v: %w", target, wait, lastErr)
+		}
+
+		fmt.Printf("Attempt %d: failed to fetch %s: %v. Retrying in %v...\n", attempt, target, lastErr, wait)
+		if err := SleepContext(ctx, wait); err != nil {
+			return nil, fmt.Errorf("giving up on %s: %w (last error: %v)", target, err, lastErr)
+		}
+	}
+
+	return nil, fmt.Errorf("failed to make request after retries: %w", lastErr)
+}
@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+	ErrRobotsUnavailable  = errors.New("robots.txt unavailable") // unreachable or 5xx, the feed is not fetched
+)
+
+const (
+	maxRobotsBytes = 512 << 10
+	maxCrawlDelay  = 30 * time.Second // longer delays would not fit in a Lambda run
+)
+
+type robotsRule struct {
+	allow   bool
+	length  int // pattern length, the longest match wins
+	pattern *regexp.Regexp
+}
+
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsEntry is fetched once per host and run; ready is closed when rules
+// and err have been filled in.
+type robotsEntry struct {
+	ready chan struct{}
+	rules robotsRules
+	err   error // wraps ErrRobotsUnavailable
+
+	next time.Time // earliest start of the next request, for Crawl-delay
+}
+
+// robotsToken extracts the product token ("numerosnumerosnumeros_bot") that
+// robots.txt groups are matched against.
+func robotsToken(userAgent string) string {
+	token, _, _ := strings.Cut(userAgent, "/")
+	return strings.ToLower(strings.TrimSpace(token))
+}
+
+// parseRobots follows RFC 9309: the groups naming our token win over "*",
+// and within them the longest matching pattern decides.
+func parseRobots(r io.Reader, token string) robotsRules {
+	var specific, wildcard robotsRules
+	var haveSpecific bool
+
+	var groupAgents []string
+	inRules := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxRobotsBytes)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+			continue
+		}
+
+		if key != "allow" && key != "disallow" && key != "crawl-delay" {
+			continue
+		}
+		inRules = true
+
+		for _, agent := range groupAgents {
+			var target *robotsRules
+			switch {
+			case agent == "*":
+				target = &wildcard
+			case agent == token:
+				target = &specific
+				haveSpecific = true
+			default:
+				continue
+			}
+
+			switch key {
+			case "allow", "disallow":
+				if value == "" {
+					continue // empty Disallow allows everything
+				}
+				target.rules = append(target.rules, robotsRule{
+					allow:   key == "allow",
+					length:  len(value),
+					pattern: compileRobotsPattern(value),
+				})
+			case "crawl-delay":
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					target.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if haveSpecific {
+		return specific
+	}
+	return wildcard
+}
+
+func (r robotsRules) allowed(u *url.URL) bool {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	best := -1
+	allow := true
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > best || (rule.length == best && rule.allow) {
+			best = rule.length
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// compileRobotsPattern supports the "*" wildcard and the "$" end anchor.
+func compileRobotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// fetchRobots reads the host's robots.txt. Unreachable hosts and server
+// errors give ErrRobotsUnavailable: the site is not to be crawled, but it is
+// an outage rather than a disallow.
+func (f *Fetcher) fetchRobots(ctx context.Context, client *http.Client, u *url.URL, userAgent string) (robotsRules, error) {
+	robotsURL := u.Scheme + "://" + u.Host + "/robots.txt"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return robotsRules{}, fmt.Errorf("%w for %s: %v", ErrRobotsUnavailable, u.Host, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return robotsRules{}, fmt.Errorf("%w for %s: %v", ErrRobotsUnavailable, u.Host, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return parseRobots(io.LimitReader(resp.Body, maxRobotsBytes), robotsToken(userAgent)), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return robotsRules{}, nil // no robots.txt: everything allowed
+	default:
+		return robotsRules{}, fmt.Errorf("%w for %s: status %d", ErrRobotsUnavailable, u.Host, resp.StatusCode)
+	}
+}
+
+func (f *Fetcher) robotsFor(ctx context.Context, client *http.Client, u *url.URL, userAgent string) *robotsEntry {
+	key := strings.ToLower(u.Scheme + "://" + u.Host)
+
+	f.mu.Lock()
+	entry, ok := f.robots[key]
+	if !ok {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		f.robots[key] = entry
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		entry.rules, entry.err = f.fetchRobots(ctx, client, u, userAgent)
+		close(entry.ready)
+	}
+
+	return entry
+}
+
+// checkRobots returns ErrDisallowedByRobots for disallowed URLs,
+// ErrRobotsUnavailable when robots.txt couldn't be read, and otherwise waits out the host's Crawl-delay before letting the request go.
+func (f *Fetcher) checkRobots(ctx context.Context, client *http.Client, rawURL, botAgent string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid feed URL: %w", err)
+	}
+
+	entry := f.robotsFor(ctx, client, u, botAgent)
+	select {
+	case <-entry.ready:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if entry.err != nil {
+		return entry.err
+	}
+	if !entry.rules.allowed(u) {
+		return fmt.Errorf("%s: %w", rawURL, ErrDisallowedByRobots)
+	}
+
+	delay := min(entry.rules.crawlDelay, maxCrawlDelay)
+	if delay <= 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	now := time.Now()
+	start := entry.next
+	if start.Before(now) {
+		start = now
+	}
+	entry.next = start.Add(delay)
+	f.mu.Unlock()
+
+	wait := time.Until(start)
+	if wait <= 0 {
+		return nil
+	}
+	return SleepContext(ctx, wait)
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/anything", true},
+		{"/feed", "/feed", true},
+		{"/feed", "/feeds/world", true},
+		{"/feed", "/news/feed", false},
+		{"/*.xml", "/rss/world.xml", true},
+		{"/*.xml", "/rss/world.html", false},
+		{"/*.xml$", "/world.xml", true},
+		{"/*.xml$", "/world.xml?page=2", false},
+		{"/search$", "/search", true},
+		{"/search$", "/search/x", false},
+		{"/a*b*c", "/a-b-c/d", true},
+		{"/a+b", "/a+b", true}, // regexp metacharacters are literal
+		{"/a+b", "/aab", false},
+		{"/?q=", "/?q=x", true},
+	}
+	for _, tt := range tests {
+		if got := compileRobotsPattern(tt.pattern).MatchString(tt.path); got != tt.want {
+			t.Errorf("pattern %q on %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name      string
+		robots    string
+		wantRules int
+		wantDelay time.Duration
+	}{
+		{"empty", "", 0, 0},
+		{"wildcard", "User-agent: *\nDisallow: /private\nAllow: /private/ok\n", 2, 0},
+		{"empty disallow", "User-agent: *\nDisallow:\n", 0, 0},
+		{"specific wins", "User-agent: *\nDisallow: /\n\nUser-agent: testbot\nDisallow: /a\nCrawl-delay: 2\n", 1, 2 * time.Second},
+		{"other bots ignored", "User-agent: otherbot\nDisallow: /\n", 0, 0},
+		{"grouped agents", "User-agent: otherbot\nUser-agent: testbot\nDisallow: /x\n", 1, 0},
+		{"comments and case", "# hi\nUSER-AGENT: TestBot # us\ndisallow: /y # no\n", 1, 0},
+		{"fractional delay", "User-agent: *\nCrawl-delay: 0.5\n", 0, 500 * time.Millisecond},
+		{"bad delay", "User-agent: *\nCrawl-delay: soon\n", 0, 0},
+		{"new group after rules", "User-agent: testbot\nDisallow: /a\nUser-agent: otherbot\nDisallow: /b\n", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRobots(strings.NewReader(tt.robots), "testbot")
+			if len(got.rules) != tt.wantRules {
+				t.Errorf("rules = %d, want %d", len(got.rules), tt.wantRules)
+			}
+			if got.crawlDelay != tt.wantDelay {
+				t.Errorf("crawl delay = %v, want %v", got.crawlDelay, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	robots := `User-agent: *
+Disallow: /
+
+User-agent: testbot
+Disallow: /private
+Allow: /private/public
+Disallow: /*.json$
+Disallow: /search?
+Allow: /page
+Disallow: /page
+`
+	rules := parseRobots(strings.NewReader(robots), "testbot")
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/", true},
+		{"https://example.com", true},
+		{"https://example.com/feed.xml", true},
+		{"https://example.com/private", false},
+		{"https://example.com/private/x", false},
+		{"https://example.com/private/public/x", true}, // longer match wins
+		{"https://example.com/data.json", false},
+		{"https://example.com/data.json?x=1", true},
+		{"https://example.com/search?q=go", false},
+		{"https://example.com/search", true},
+		{"https://example.com/page", true}, // equal length: allow wins
+		{"https://example.com/robots.txt", true},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rules.allowed(u); got != tt.want {
+			t.Errorf("allowed(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	everything := parseRobots(strings.NewReader("User-agent: *\nDisallow: /\n"), "testbot")
+	if u, _ := url.Parse("https://example.com/feed"); everything.allowed(u) {
+		t.Error("Disallow: / allowed /feed")
+	}
+}
@@ -1,41 +1,39 @@
 package tools
 
 import (
-	"bytes"
 	"context"
-	"encoding/xml"
 	"fmt"
-	"html"
-	"io"
 	"net/http"
 	"strings"
-	"time"
 
 	"numerosnumerosnumeros_agg/feeds"
 	"numerosnumerosnumeros_agg/typesPkg"
-
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/transform"
 )
 
-type AtomFeed struct {
-	Entries []AtomEntry `xml:"entry"`
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"` // often "unknown" or "1,234", see parseLength
 }
 
 type AtomEntry struct {
-	Title string `xml:"title"`
-	Link  struct {
-		Href string `xml:"href,attr"`
-	} `xml:"link"`
-	ID string `xml:"id"`
-}
-
-type RSS struct {
-	Channel Channel `xml:"channel"`
-}
-
-type Channel struct {
-	Items []Item `xml:"item"`
+	Title     string     `xml:"title"`
+	Links     []AtomLink `xml:"link"`
+	ID        string     `xml:"id"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+	MediaThumbnails []MediaRef `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaContents   []MediaRef `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroup      MediaGroup `xml:"http://search.yahoo.com/mrss/ group"`
 }
 
 type Item struct {
@@ -46,208 +44,332 @@ type Item struct {
 	AtomLink struct {
 		Href string `xml:"href,attr"`
 	} `xml:"http://www.w3.org/2005/Atom link"`
+	PubDate        string   `xml:"pubDate"`
+	DCDate         string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description    string   `xml:"description"`
+	ContentEncoded string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Comments       string   `xml:"comments"`
+	Author         string   `xml:"author"`
+	Creator        string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories     []string `xml:"category"`
+	Enclosures     []struct {
+		URL    string `xml:"url,attr"`
+		Type   string `xml:"type,attr"`
+		Length string `xml:"length,attr"`
+	} `xml:"enclosure"`
+	MediaThumbnails []MediaRef `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaContents   []MediaRef `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroup      MediaGroup `xml:"http://search.yahoo.com/mrss/ group"`
 }
 
-type SlashdotRDF struct {
-	Items []SlashdotItem `xml:"item"`
+type SlashdotItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
 }
 
-type SlashdotItem struct {
-	Title string `xml:"title"`
-	Link  string `xml:"link"`
+const (
+	defaultMaxFeedBytes = 5 << 20
+	defaultMaxFeedItems = 200
+)
+
+type FeedResult struct {
+	Posts       []typesPkg.MainStruct
+	Validators  typesPkg.FeedValidators // validators to send on the next run
+	NotModified bool                    // server answered 304, Posts is empty
+	Warnings    []string                // non-fatal problems, e.g. truncation
 }
 
-func ParseRSSFeed(ctx context.Context, userAgents typesPkg.Agents, feed feeds.FeedConfig) ([]typesPkg.MainStruct, error) {
-	client := &http.Client{
-		Timeout: 40 * time.Second,
+// ParseRSSFeed fetches and decodes one feed. jar may be nil; when given it
+// stores and replays the site's cookies.
+func ParseRSSFeed(ctx context.Context, fetcher *Fetcher, userAgents typesPkg.Agents, feed feeds.FeedConfig, validators typesPkg.FeedValidators, jar *CookieJar) (FeedResult, error) {
+	if feed.Source == sourceEmail {
+		return parseEmailFeed(ctx, feed, validators)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", feed.URL, nil)
+	release, err := fetcher.acquire(ctx, feed)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return FeedResult{}, err
 	}
+	defer release()
 
-	var selectedUserAgent string
-	switch feed.Agent {
-	case "chrome":
-		selectedUserAgent = userAgents.Chrome
-	case "reader":
-		selectedUserAgent = userAgents.Reader
-	case "bot":
-		selectedUserAgent = userAgents.Bot
-	default:
-		selectedUserAgent = userAgents.Bot
+	profile, err := resolveProfile(feed)
+	if err != nil {
+		return FeedResult{}, err
 	}
 
-	req.Header.Set("User-Agent", selectedUserAgent)
-	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml, */*")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	req.Header.Set("Cache-Control", "no-cache")
+	client, err := fetcher.clientFor(feed)
+	if err != nil {
+		return FeedResult{}, err
+	}
 
-	if feed.EnhancedHeaders {
-		req.Header.Set("Sec-Fetch-Dest", "document")
-		req.Header.Set("Sec-Fetch-Mode", "navigate")
-		req.Header.Set("Sec-Fetch-Site", "none")
+	if jar != nil {
+		withJar := *client
+		withJar.Jar = jar
+		client = &withJar
 	}
 
-	var resp *http.Response
-	const maxRetries = 3
+	requestURL, err := feedRequestURL(feed)
+	if err != nil {
+		return FeedResult{}, err
+	}
 
-	for i := range maxRetries {
-		resp, err = client.Do(req)
-		if err == nil {
-			break
-		}
+	if err := fetcher.checkRobots(ctx, client, requestURL, userAgents.Bot); err != nil {
+		return FeedResult{}, err
+	}
 
-		if i < maxRetries-1 {
-			wait := time.Duration(500*(1<<i)) * time.Millisecond // Exponential backoff
-			fmt.Printf("Attempt %d: failed to fetch %s: %v. Retrying in %v...\n", i+1, feed.URL, err, wait)
-			time.Sleep(wait)
-		}
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return FeedResult{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	applyProfile(req, profile, userAgents)
+
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
 	}
 
+	resp, err := FeedRetryPolicy.Do(ctx, client, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request after retries: %w", err)
+		return FeedResult{}, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotModified {
+		return FeedResult{Validators: validators, NotModified: true}, nil
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		return FeedResult{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	if feed.Header == "Slashdot" {
-		reader := transform.NewReader(bytes.NewReader(body), charmap.ISO8859_1.NewDecoder())
-		convertedBody, err := io.ReadAll(reader)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert encoding: %w", err)
-		}
+	newValidators := typesPkg.FeedValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
 
-		// Remove the encoding declaration since we've converted to UTF-8
-		bodyStr := string(convertedBody)
-		bodyStr = strings.Replace(bodyStr, `encoding="ISO-8859-1"`, `encoding="UTF-8"`, 1)
-		body = []byte(bodyStr)
+	maxBytes := feed.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxFeedBytes
+	}
+	maxItems := feed.MaxItems
+	if maxItems <= 0 {
+		maxItems = defaultMaxFeedItems
 	}
 
-	var posts []typesPkg.MainStruct
+	body := &limitedReader{r: resp.Body, remaining: maxBytes}
 
-	if feed.Header == "Slashdot" {
-		var slashdotRDF SlashdotRDF
-		if err := xml.Unmarshal(body, &slashdotRDF); err != nil {
-			return nil, fmt.Errorf("failed to parse Slashdot RDF XML: %w", err)
-		}
+	var posts []typesPkg.MainStruct
+	var capped bool
+	emit := func(post typesPkg.MainStruct) {
+		posts = append(posts, post)
+	}
+	switch {
+	case feed.Source == sourceHN && isHNAPI(req):
+		posts, capped, err = fetchHNAPIStories(ctx, client, req, body, feed, maxItems)
+	case feed.Source == sourceHTML:
+		capped, err = streamHTMLListing(body, resp.Header.Get("Content-Type"), resp.Request.URL, feed, maxItems, emit)
+	default:
+		capped, err = streamFeed(body, resp.Header.Get("Content-Type"), feed, maxItems, emit)
+	}
 
-		for _, item := range slashdotRDF.Items {
-			title := html.UnescapeString(strings.TrimSpace(item.Title))
+	var warnings []string
+	if body.truncated {
+		warnings = append(warnings, fmt.Sprintf("feed exceeded %d bytes, kept the first %d items", maxBytes, len(posts)))
+	} else if err != nil {
+		return FeedResult{}, err
+	}
+	if capped {
+		warnings = append(warnings, fmt.Sprintf("feed has more than %d items, the rest were skipped", maxItems))
+	}
 
-			if title == "" || item.Link == "" {
-				continue
-			}
+	if len(posts) == 0 {
+		return FeedResult{}, fmt.Errorf("no news releases found in feed")
+	}
 
-			post := typesPkg.MainStruct{
-				GUID:   item.Link,
-				Title:  title,
-				Header: feed.Header,
-				Link:   item.Link,
-			}
+	return FeedResult{Posts: posts, Validators: newValidators, Warnings: warnings}, nil
+}
 
-			posts = append(posts, post)
+// feedRequestURL is the URL actually fetched for a feed. Reddit feeds keep
+// their .rss URL in the config (it names the feed's state), but are read
+// from the listing JSON, which carries scores and the outbound link.
+func feedRequestURL(feed feeds.FeedConfig) (string, error) {
+	switch feed.Source {
+	case "", sourceHN:
+		return feed.URL, nil
+	case sourceHTML:
+		if _, err := compileScrapeRules(feed.Scrape); err != nil {
+			return "", err
 		}
-		// Check if it's an Atom feed
-	} else if strings.HasPrefix(feed.Header, "r/") {
-		var atomFeed AtomFeed
-		if err := xml.Unmarshal(body, &atomFeed); err != nil {
-			return nil, fmt.Errorf("failed to parse Atom XML: %w", err)
+		return feed.URL, nil
+	case sourceReddit:
+		if feed.Format == "atom" {
+			return feed.URL, nil
 		}
+		return redditListingURL(feed.URL)
+	default:
+		return "", fmt.Errorf("unknown feed source %q", feed.Source)
+	}
+}
+
+// RankedListing reports whether the feed lists stories by rank rather than
+// by date. Items carry their submission time, so one can reach the listing
+// after a newer one was already sent.
+func RankedListing(feed feeds.FeedConfig) bool {
+	return feed.Source == sourceReddit || feed.Source == sourceHN
+}
+
+func rdfItemToPost(item SlashdotItem, feed feeds.FeedConfig) (typesPkg.MainStruct, bool) {
+	title := strings.TrimSpace(item.Title)
+
+	if title == "" || item.Link == "" {
+		return typesPkg.MainStruct{}, false
+	}
+
+	published, _ := ParseDate(item.Date)
+
+	return typesPkg.MainStruct{
+		GUID:        item.Link,
+		Title:       title,
+		Header:      feed.Header,
+		Link:        item.Link,
+		Published:   published,
+		Description: strings.TrimSpace(item.Description),
+		Author:      strings.TrimSpace(item.Creator),
+		Categories:  cleanCategories(item.Subjects),
+	}, true
+}
+
+func atomEntryToPost(entry AtomEntry, feed feeds.FeedConfig) (typesPkg.MainStruct, bool) {
+	title := strings.TrimSpace(entry.Title)
+	if title == "" {
+		return typesPkg.MainStruct{}, false
+	}
 
-		h := strings.ReplaceAll(feed.Header, " ", "")
-
-		for _, entry := range atomFeed.Entries {
-			title := strings.TrimSpace(entry.Title)
-			if title == "" {
-				continue
-			}
-
-			link := strings.TrimSpace(entry.Link.Href)
-			candidate := strings.TrimSpace(entry.ID)
-
-			var guid string
-			if candidate != "" {
-				if h != "" {
-					guid = h + ":" + candidate
-				} else {
-					guid = candidate
-				}
-			} else if link != "" {
-				guid = link // fallback — do NOT prefix
-			} else {
-				continue
-			}
-
-			post := typesPkg.MainStruct{
-				GUID:   guid,
-				Title:  title,
-				Header: feed.Header,
-				Link:   link,
-			}
-			posts = append(posts, post)
+	h := strings.ReplaceAll(feed.Header, " ", "")
+
+	link, enclosures := atomLinks(entry.Links)
+	candidate := strings.TrimSpace(entry.ID)
+
+	var guid string
+	if candidate != "" {
+		if h != "" {
+			guid = h + ":" + candidate
+		} else {
+			guid = candidate
 		}
+	} else if link != "" {
+		guid = link // fallback — do NOT prefix
 	} else {
-		// Parse as RSS
-		var rss RSS
-		if err := xml.Unmarshal(body, &rss); err != nil {
-			return nil, fmt.Errorf("failed to parse RSS XML: %w", err)
-		}
+		return typesPkg.MainStruct{}, false
+	}
+
+	published, _ := ParseDate(entry.Published)
+	updated, _ := ParseDate(entry.Updated)
+	if published.IsZero() {
+		published = updated
+	}
+
+	description := strings.TrimSpace(entry.Summary)
+	if description == "" {
+		description = strings.TrimSpace(entry.Content)
+	}
+
+	var author string
+	if len(entry.Authors) > 0 {
+		author = strings.TrimSpace(entry.Authors[0].Name)
+	}
 
-		h := strings.ReplaceAll(feed.Header, " ", "")
-
-		for _, item := range rss.Channel.Items {
-			title := strings.TrimSpace(item.Title)
-			link := strings.TrimSpace(item.Link)
-
-			if link == "" && item.AtomLink.Href != "" {
-				link = strings.TrimSpace(item.AtomLink.Href)
-			}
-			if link == "" && strings.TrimSpace(item.GUID) != "" {
-				link = strings.TrimSpace(item.GUID)
-			}
-			if title == "" || link == "" {
-				continue
-			}
-
-			candidate := strings.TrimSpace(item.GUID)
-			if candidate == "" {
-				candidate = strings.TrimSpace(item.ItemID)
-			}
-
-			var guid string
-			if candidate != "" {
-				if h != "" {
-					guid = h + ":" + candidate
-				} else {
-					guid = candidate
-				}
-			} else {
-				guid = link // fallback — do NOT prefix
-			}
-
-			post := typesPkg.MainStruct{
-				GUID:   guid,
-				Title:  title,
-				Header: feed.Header,
-				Link:   link,
-			}
-			posts = append(posts, post)
+	categories := make([]string, 0, len(entry.Categories))
+	for _, c := range entry.Categories {
+		categories = append(categories, c.Term)
+	}
+
+	return typesPkg.MainStruct{
+		GUID:        guid,
+		Title:       title,
+		Header:      feed.Header,
+		Link:        link,
+		Published:   published,
+		Updated:     updated,
+		Description: description,
+		Author:      author,
+		Categories:  cleanCategories(categories),
+		Enclosures:  enclosures,
+		Image:       pickImage(entry.MediaThumbnails, entry.MediaContents, entry.MediaGroup, enclosures),
+	}, true
+}
+
+func rssItemToPost(item Item, feed feeds.FeedConfig) (typesPkg.MainStruct, bool) {
+	title := strings.TrimSpace(item.Title)
+	link := strings.TrimSpace(item.Link)
+
+	if link == "" && item.AtomLink.Href != "" {
+		link = strings.TrimSpace(item.AtomLink.Href)
+	}
+	if link == "" && strings.TrimSpace(item.GUID) != "" {
+		link = strings.TrimSpace(item.GUID)
+	}
+	if title == "" || link == "" {
+		return typesPkg.MainStruct{}, false
+	}
+
+	h := strings.ReplaceAll(feed.Header, " ", "")
+
+	candidate := strings.TrimSpace(item.GUID)
+	if candidate == "" {
+		candidate = strings.TrimSpace(item.ItemID)
+	}
+
+	var guid string
+	if candidate != "" {
+		if h != "" {
+			guid = h + ":" + candidate
+		} else {
+			guid = candidate
 		}
+	} else {
+		guid = link // fallback — do NOT prefix
 	}
 
-	if len(posts) == 0 {
-		return nil, fmt.Errorf("no news releases found in feed")
+	published, _ := ParseDate(item.PubDate)
+	if published.IsZero() {
+		published, _ = ParseDate(item.DCDate)
+	}
+
+	description := strings.TrimSpace(item.Description)
+	if description == "" {
+		description = strings.TrimSpace(item.ContentEncoded)
+	}
+
+	author := strings.TrimSpace(item.Creator)
+	if author == "" {
+		author = strings.TrimSpace(item.Author)
+	}
+
+	enclosures := make([]typesPkg.Enclosure, 0, len(item.Enclosures))
+	for _, e := range item.Enclosures {
+		if u := strings.TrimSpace(e.URL); u != "" {
+			enclosures = append(enclosures, typesPkg.Enclosure{URL: u, Type: strings.TrimSpace(e.Type), Length: parseLength(e.Length)})
+		}
 	}
 
-	return posts, nil
+	return typesPkg.MainStruct{
+		GUID:        guid,
+		Title:       title,
+		Header:      feed.Header,
+		Link:        link,
+		Published:   published,
+		Description: description,
+		Author:      author,
+		Categories:  cleanCategories(item.Categories),
+		Enclosures:  enclosures,
+		Image:       pickImage(item.MediaThumbnails, item.MediaContents, item.MediaGroup, enclosures),
+	}, true
 }
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+func testRSS(items int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Test</title>`)
+	for i := range items {
+		fmt.Fprintf(&b, `<item><title>Story number %d</title><link>https://example.com/%d</link><guid>%d</guid></item>`, i, i, i)
+	}
+	b.WriteString(`</channel></rss>`)
+	return b.String()
+}
+
+func TestParseRSSFeedLimits(t *testing.T) {
+	body := testRSS(5)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	// Cut the body in the middle of the third item
+	cut := strings.Index(body, "Story number 2") + 5
+
+	tests := []struct {
+		name        string
+		maxBytes    int64
+		maxItems    int
+		wantPosts   int
+		wantWarning string
+	}{
+		{"no limits hit", 0, 0, 5, ""},
+		{"truncated body", int64(cut), 0, 2, fmt.Sprintf("feed exceeded %d bytes, kept the first 2 items", cut)},
+		{"item cap", 0, 3, 3, "feed has more than 3 items, the rest were skipped"},
+	}
+	for _, tt := range tests {
+		fc := feeds.FeedConfig{URL: srv.URL + "/feed.xml", Header: "Test", MaxBytes: tt.maxBytes, MaxItems: tt.maxItems}
+		res, err := ParseRSSFeed(context.Background(), NewFetcher(FetcherOptions{}), NewAgents("test@example.com"), fc, typesPkg.FeedValidators{}, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(res.Posts) != tt.wantPosts {
+			t.Errorf("%s: %d posts, want %d", tt.name, len(res.Posts), tt.wantPosts)
+		}
+		switch {
+		case tt.wantWarning == "" && len(res.Warnings) > 0:
+			t.Errorf("%s: unexpected warnings %q", tt.name, res.Warnings)
+		case tt.wantWarning != "" && (len(res.Warnings) != 1 || res.Warnings[0] != tt.wantWarning):
+			t.Errorf("%s: warnings %q, want %q", tt.name, res.Warnings, tt.wantWarning)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html/charset"
+)
+
+const sourceHTML = "html"
+
+type scrapeSelectors struct {
+	item, title, link, date, description, image goquery.Matcher
+}
+
+// compileScrapeRules parses every selector up front; goquery would silently
+// match nothing on a typo.
+func compileScrapeRules(rules feeds.ScrapeRules) (scrapeSelectors, error) {
+	if strings.TrimSpace(rules.Item) == "" {
+		return scrapeSelectors{}, fmt.Errorf("html source needs a Scrape.Item selector")
+	}
+
+	var sels scrapeSelectors
+	for _, s := range []struct {
+		name string
+		raw  string
+		dst  *goquery.Matcher
+	}{
+		{"Item", rules.Item, &sels.item},
+		{"Title", rules.Title, &sels.title},
+		{"Link", rules.Link, &sels.link},
+		{"Date", rules.Date, &sels.date},
+		{"Description", rules.Description, &sels.description},
+		{"Image", rules.Image, &sels.image},
+	} {
+		if strings.TrimSpace(s.raw) == "" {
+			continue
+		}
+		compiled, err := cascadia.Compile(s.raw)
+		if err != nil {
+			return scrapeSelectors{}, fmt.Errorf("invalid Scrape.%s selector %q: %w", s.name, s.raw, err)
+		}
+		*s.dst = compiled
+	}
+	return sels, nil
+}
+
+// streamHTMLListing scrapes a listing page with the feed's Scrape rules.
+// Relative links are resolved against pageURL (or the page's <base href>).
+func streamHTMLListing(r io.Reader, contentType string, pageURL *url.URL, feed feeds.FeedConfig, maxItems int, emit func(typesPkg.MainStruct)) (bool, error) {
+	sels, err := compileScrapeRules(feed.Scrape)
+	if err != nil {
+		return false, err
+	}
+
+	utf8, err := charset.NewReader(r, contentType)
+	if err != nil {
+		return false, fmt.Errorf("failed to read listing page: %w", err)
+	}
+	doc, err := goquery.NewDocumentFromReader(utf8)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse listing page: %w", err)
+	}
+
+	base := documentBase(doc, pageURL)
+	if doc.FindMatcher(sels.item).Length() == 0 {
+		return false, fmt.Errorf("no elements match Scrape.Item %q", feed.Scrape.Item)
+	}
+
+	posts := scrapeDocument(doc, sels, base, feed)
+	for i, post := range posts {
+		if i >= maxItems {
+			return true, nil
+		}
+		emit(post)
+	}
+	return false, nil
+}
+
+// documentBase is the URL relative links in doc resolve against.
+func documentBase(doc *goquery.Document, pageURL *url.URL) *url.URL {
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := pageURL.Parse(strings.TrimSpace(href)); err == nil {
+			return u
+		}
+	}
+	return pageURL
+}
+
+// scrapeDocument turns every element matching sels.item into a post, once
+// per link.
+func scrapeDocument(doc *goquery.Document, sels scrapeSelectors, base *url.URL, feed feeds.FeedConfig) []typesPkg.MainStruct {
+	var posts []typesPkg.MainStruct
+	seen := make(map[string]bool)
+	doc.FindMatcher(sels.item).Each(func(_ int, item *goquery.Selection) {
+		post, ok := scrapeItemToPost(item, sels, base, feed)
+		if ok && !seen[post.Link] {
+			seen[post.Link] = true
+			posts = append(posts, post)
+		}
+	})
+	return posts
+}
+
+func scrapeItemToPost(item *goquery.Selection, sels scrapeSelectors, base *url.URL, feed feeds.FeedConfig) (typesPkg.MainStruct, bool) {
+	var anchor *goquery.Selection
+	switch {
+	case sels.link != nil:
+		anchor = item.FindMatcher(sels.link).First()
+	case item.Is("a[href]"):
+		anchor = item
+	default:
+		anchor = item.Find("a[href]").First()
+	}
+
+	href, _ := anchor.Attr("href")
+	link := absoluteURL(base, href)
+	if link == "" {
+		return typesPkg.MainStruct{}, false
+	}
+
+	title := squashSpaces(anchor.Text())
+	if sels.title != nil {
+		title = squashSpaces(item.FindMatcher(sels.title).First().Text())
+	}
+	if title == "" {
+		return typesPkg.MainStruct{}, false
+	}
+
+	var published time.Time
+	if sels.date != nil {
+		published = scrapeDate(item.FindMatcher(sels.date).First(), feed.Scrape.DateLayout)
+	}
+
+	var description string
+	if sels.description != nil {
+		description = squashSpaces(item.FindMatcher(sels.description).First().Text())
+	}
+
+	var image string
+	if sels.image != nil {
+		img := item.FindMatcher(sels.image).First()
+		src, ok := img.Attr("src")
+		if !ok || strings.HasPrefix(src, "data:") {
+			src, _ = img.Attr("data-src")
+		}
+		image = absoluteURL(base, src)
+	}
+
+	h := strings.ReplaceAll(feed.Header, " ", "")
+
+	return typesPkg.MainStruct{
+		GUID:        h + ":" + link,
+		Title:       title,
+		Header:      feed.Header,
+		Link:        link,
+		Published:   published,
+		Description: description,
+		Image:       image,
+	}, true
+}
+
+func scrapeDate(sel *goquery.Selection, layout string) time.Time {
+	raw, ok := sel.Attr("datetime")
+	if !ok {
+		raw, ok = sel.Attr("content")
+	}
+	if !ok {
+		raw = sel.Text()
+	}
+	raw = squashSpaces(raw)
+
+	if layout != "" {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.UTC()
+		}
+	}
+	t, _ := ParseDate(raw)
+	return t
+}
+
+// absoluteURL resolves href against base and keeps only http(s) links.
+func absoluteURL(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	u, err := base.Parse(href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
+
+func squashSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestScrapeDate(t *testing.T) {
+	tests := []struct {
+		html   string
+		layout string
+		want   time.Time
+	}{
+		{`<time datetime="2026-10-18T10:00:00+02:00">x</time>`, "", time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)},
+		{`<meta content="2026-10-18T10:00:00Z">`, "", time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)},
+		{`<span>18.10.2026 10:00 +0200</span>`, "02.01.2006 15:04 -0700", time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)},
+		{`<span>yesterday</span>`, "02.01.2006", time.Time{}},
+	}
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := scrapeDate(doc.Find("time, meta, span").First(), tt.layout)
+		if !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("scrapeDate(%s, %q) = %v, want %v in UTC", tt.html, tt.layout, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+// Words that carry no story identity and only add noise to short titles
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true,
+	"of": true, "to": true, "in": true, "on": true, "at": true, "for": true,
+	"by": true, "with": true, "from": true, "as": true, "is": true, "are": true,
+	"was": true, "were": true, "be": true, "been": true, "it": true, "its": true,
+	"this": true, "that": true, "after": true, "over": true, "into": true,
+	"says": true, "said": true, "new": true,
+}
+
+const (
+	// Only the start of a description is used, the rest tends to be boilerplate
+	maxDescriptionWords = 40
+
+	// Titles sharing fewer words than this are never the same story, however
+	// short they are
+	minSharedWords = 3
+)
+
+// storyWords lowercases text and splits it into words of letters and digits,
+// dropping stop words and a plural "s".
+func storyWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	words := fields[:0]
+	for _, w := range fields {
+		if stopWords[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = w[:len(w)-1]
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+// StoryWords is the sorted word set an article is compared by: its title
+// and, optionally, the start of its description.
+func StoryWords(art typesPkg.MainStruct, withDescription bool) []string {
+	words := storyWords(art.Title) // already plain, see NormalizeTitles
+	if withDescription && art.Description != "" {
+		desc := storyWords(plainText(art.Description))
+		if len(desc) > maxDescriptionWords {
+			desc = desc[:maxDescriptionWords]
+		}
+		words = append(words, desc...)
+	}
+
+	sort.Strings(words)
+	set := words[:0]
+	for i, w := range words {
+		if i == 0 || w != words[i-1] {
+			set = append(set, w)
+		}
+	}
+	return set
+}
+
+// Similarity is the Jaccard similarity of two sorted word sets (shared words
+// over all words), or 0 when they share fewer than minSharedWords.
+//
+// It is computed exactly rather than estimated with MinHash or SimHash:
+// titles are a handful of words and only a few days of stories are compared,
+// so the exact value is cheap, and SimHash distances on text this short did
+// not separate rewrites of one story from unrelated headlines.
+func Similarity(a, b []string) float64 {
+	shared := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	if shared < minSharedWords {
+		return 0
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"testing"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+func TestSimilaritySameStory(t *testing.T) {
+	pairs := [][2]string{
+		{"Russia launches massive drone attack on Kyiv", "Russia launches massive drone attack on Kyiv overnight"},
+		{"Fed cuts interest rates by a quarter point", "Federal Reserve cuts interest rates by quarter point"},
+		{"Israel and Hamas agree to ceasefire deal", "Israel, Hamas agree ceasefire deal in Gaza"},
+		{"Apple unveils iPhone 17 at September event", "Apple unveils the iPhone 17 and iPhone Air"},
+		{"OpenAI announces GPT-5", "OpenAI launches GPT-5"},
+		{"Nvidia becomes first company worth $5 trillion", "Nvidia becomes the first $5 trillion company"},
+	}
+	for _, p := range pairs {
+		sim := titleSimilarity(p[0], p[1])
+		if sim < feeds.Dedup.MinSimilarity {
+			t.Errorf("%q vs %q: similarity %.2f, want at least %.2f", p[0], p[1], sim, feeds.Dedup.MinSimilarity)
+		}
+	}
+}
+
+func TestSimilarityDifferentStory(t *testing.T) {
+	pairs := [][2]string{
+		{"UK inflation falls to 2%", "UK inflation rises to 4%"},
+		{"Trump meets Putin in Alaska", "Trump meets Zelensky at the White House"},
+		{"Bank of England holds rates at 5%", "European Central Bank cuts rates to 3%"},
+		{"SpaceX launches Starship on tenth test flight", "Blue Origin launches New Glenn rocket"},
+		{"Google fined €2.9bn by EU over adtech", "Apple fined €500m by EU over App Store rules"},
+		{"Earthquake hits Afghanistan", "Floods hit Pakistan"},
+	}
+	for _, p := range pairs {
+		sim := titleSimilarity(p[0], p[1])
+		if sim >= feeds.Dedup.MinSimilarity {
+			t.Errorf("%q vs %q: similarity %.2f, want below %.2f", p[0], p[1], sim, feeds.Dedup.MinSimilarity)
+		}
+	}
+}
+
+func TestStoryWords(t *testing.T) {
+	got := StoryWords(typesPkg.MainStruct{Title: "The Fed cuts rates, and cuts again"}, false)
+	want := []string{"again", "cut", "fed", "rate"}
+	if len(got) != len(want) {
+		t.Fatalf("StoryWords = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("StoryWords = %q, want %q", got, want)
+		}
+	}
+}
+
+func titleSimilarity(a, b string) float64 {
+	return Similarity(
+		StoryWords(typesPkg.MainStruct{Title: a}, false),
+		StoryWords(typesPkg.MainStruct{Title: b}, false),
+	)
+}
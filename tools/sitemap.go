@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"strings"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+// SitemapURL is a <url> of a Google News sitemap. Entries without a
+// <news:news> block are ordinary pages and are skipped.
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+	News    *struct {
+		PublicationDate string `xml:"http://www.google.com/schemas/sitemap-news/0.9 publication_date"`
+		Title           string `xml:"http://www.google.com/schemas/sitemap-news/0.9 title"`
+		Keywords        string `xml:"http://www.google.com/schemas/sitemap-news/0.9 keywords"`
+	} `xml:"http://www.google.com/schemas/sitemap-news/0.9 news"`
+	Images []struct {
+		Loc string `xml:"http://www.google.com/schemas/sitemap-image/1.1 loc"`
+	} `xml:"http://www.google.com/schemas/sitemap-image/1.1 image"`
+}
+
+func sitemapURLToPost(entry SitemapURL, feed feeds.FeedConfig) (typesPkg.MainStruct, bool) {
+	link := strings.TrimSpace(entry.Loc)
+	if link == "" || entry.News == nil {
+		return typesPkg.MainStruct{}, false
+	}
+
+	title := strings.TrimSpace(entry.News.Title)
+	if title == "" {
+		return typesPkg.MainStruct{}, false
+	}
+
+	published, _ := ParseDate(entry.News.PublicationDate)
+	updated, _ := ParseDate(entry.LastMod)
+	if published.IsZero() {
+		published = updated
+	}
+
+	var image string
+	for _, img := range entry.Images {
+		if u := strings.TrimSpace(img.Loc); u != "" {
+			image = u
+			break
+		}
+	}
+
+	h := strings.ReplaceAll(feed.Header, " ", "")
+
+	return typesPkg.MainStruct{
+		GUID:       h + ":" + link,
+		Title:      title,
+		Header:     feed.Header,
+		Link:       link,
+		Published:  published,
+		Updated:    updated,
+		Categories: cleanCategories(strings.Split(entry.News.Keywords, ",")),
+		Image:      image,
+	}, true
+}
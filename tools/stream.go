@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+// limitedReader stops after remaining bytes and remembers whether the
+// underlying stream had more to give.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	truncated bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n > 0 {
+			l.truncated = true
+		}
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// streamFeed decodes the feed item by item, calling emit for every usable
+// post. It stops after maxItems items and reports whether that cap was hit.
+func streamFeed(r io.Reader, contentType string, feed feeds.FeedConfig, maxItems int, emit func(typesPkg.MainStruct)) (bool, error) {
+	format, err := resolveFormat(feed.Format)
+	if err != nil {
+		return false, err
+	}
+
+	br := bufio.NewReader(r)
+	if format == formatUnknown && looksLikeJSON(br) {
+		format = formatJSON
+	}
+
+	if format == formatJSON {
+		if head, _ := br.Peek(3); bytes.Equal(head, []byte("\xef\xbb\xbf")) {
+			_, _ = br.Discard(3)
+		}
+		if feed.Source == sourceReddit {
+			return streamRedditListing(br, feed, maxItems, emit)
+		}
+		return streamJSONFeed(br, feed, maxItems, emit)
+	}
+
+	return streamXMLFeed(br, contentType, feed, format, maxItems, emit)
+}
+
+func looksLikeJSON(br *bufio.Reader) bool {
+	head, _ := br.Peek(512)
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return len(head) > 0 && head[0] == '{'
+}
+
+func streamXMLFeed(r io.Reader, contentType string, feed feeds.FeedConfig, format feedFormat, maxItems int, emit func(typesPkg.MainStruct)) (bool, error) {
+	decoder, err := newXMLDecoder(r, contentType)
+	if err != nil {
+		return false, err
+	}
+
+	sawRoot := false
+	count := 0
+
+	for {
+		tok, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to parse %s XML: %w", format, err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if !sawRoot {
+			sawRoot = true
+			if format == formatUnknown {
+				if format, err = formatFromRoot(start); err != nil {
+					return false, fmt.Errorf("failed to detect feed format: %w", err)
+				}
+			}
+			continue
+		}
+
+		switch {
+		case format == formatRSS && start.Name.Local == "item":
+		case format == formatRDF && start.Name.Local == "item":
+		case format == formatAtom && start.Name.Local == "entry":
+		case format == formatSitemap && start.Name.Local == "url":
+		default:
+			continue
+		}
+
+		if count >= maxItems {
+			return true, nil
+		}
+		count++
+
+		var post typesPkg.MainStruct
+		var usable bool
+
+		switch format {
+		case formatRSS:
+			var item Item
+			if err := decoder.DecodeElement(&item, &start); err != nil {
+				return false, fmt.Errorf("failed to parse RSS item: %w", err)
+			}
+			post, usable = rssItemToPost(item, feed)
+			if usable && feed.Source == sourceHN {
+				post = withHNStats(post, item)
+			}
+		case formatRDF:
+			var item SlashdotItem
+			if err := decoder.DecodeElement(&item, &start); err != nil {
+				return false, fmt.Errorf("failed to parse RDF item: %w", err)
+			}
+			post, usable = rdfItemToPost(item, feed)
+		case formatAtom:
+			var entry AtomEntry
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return false, fmt.Errorf("failed to parse Atom entry: %w", err)
+			}
+			post, usable = atomEntryToPost(entry, feed)
+		case formatSitemap:
+			var entry SitemapURL
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return false, fmt.Errorf("failed to parse sitemap url: %w", err)
+			}
+			post, usable = sitemapURLToPost(entry, feed)
+		}
+
+		if usable {
+			emit(post)
+		}
+	}
+
+	if !sawRoot {
+		return false, errors.New("failed to detect feed format: no root element found")
+	}
+
+	return false, nil
+}
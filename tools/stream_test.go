@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+// streamFixture decodes testdata/name with streamFeed.
+func streamFixture(t *testing.T, name, contentType string, maxItems int) ([]typesPkg.MainStruct, bool) {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var posts []typesPkg.MainStruct
+	capped, err := streamFeed(f, contentType, feeds.FeedConfig{Header: "Test"}, maxItems, func(p typesPkg.MainStruct) {
+		posts = append(posts, p)
+	})
+	if err != nil {
+		t.Fatalf("streamFeed(%s): %v", name, err)
+	}
+	return posts, capped
+}
+
+func TestStreamFeedEnclosureLength(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []int64
+	}{
+		{"enclosure_length.rss", []int64{0, 0, 5678}},
+		{"enclosure_length.atom", []int64{0}},
+	}
+	for _, tt := range tests {
+		posts, _ := streamFixture(t, tt.fixture, "", 10)
+		if len(posts) != len(tt.want) {
+			t.Fatalf("%s: %d posts, want %d", tt.fixture, len(posts), len(tt.want))
+		}
+		for i, post := range posts {
+			if len(post.Enclosures) != 1 {
+				t.Fatalf("%s item %d: %d enclosures, want 1", tt.fixture, i, len(post.Enclosures))
+			}
+			if got := post.Enclosures[0].Length; got != tt.want[i] {
+				t.Errorf("%s item %d: length %d, want %d", tt.fixture, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	tests := []struct {
+		size, limit   int
+		wantRead      int
+		wantTruncated bool
+	}{
+		{0, 10, 0, false},
+		{5, 10, 5, false},
+		{10, 10, 10, false},
+		{11, 10, 10, true},
+		{4096, 100, 100, true},
+		{1, 0, 0, true},
+	}
+	for _, tt := range tests {
+		l := &limitedReader{r: strings.NewReader(strings.Repeat("x", tt.size)), remaining: int64(tt.limit)}
+		got, err := io.ReadAll(l)
+		if err != nil {
+			t.Errorf("size %d, limit %d: %v", tt.size, tt.limit, err)
+		}
+		if len(got) != tt.wantRead || l.truncated != tt.wantTruncated {
+			t.Errorf("size %d, limit %d: read %d, truncated %v; want %d, %v",
+				tt.size, tt.limit, len(got), l.truncated, tt.wantRead, tt.wantTruncated)
+		}
+	}
+}
+
+func TestStreamJSONFeed(t *testing.T) {
+	posts, capped := streamFixture(t, "feed.json", "application/feed+json", 10)
+	if capped {
+		t.Error("capped below the item limit")
+	}
+
+	want := []typesPkg.MainStruct{
+		{
+			GUID:        "Test:post-1",
+			Title:       "First post",
+			Header:      "Test",
+			Link:        "https://example.com/1",
+			Published:   time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+			Updated:     time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			Description: "Short summary",
+			Author:      "New Author",
+			Categories:  []string{"Tech", "AI"},
+			Enclosures:  []typesPkg.Enclosure{{URL: "https://example.com/1.mp3", Type: "audio/mpeg", Length: 1234}},
+			Image:       "https://example.com/1.jpg",
+		},
+		{
+			GUID:        "Test:42",
+			Title:       "Numeric id",
+			Header:      "Test",
+			Link:        "https://other.example/2",
+			Description: "Only content text",
+			Author:      "Solo",
+			Image:       "https://example.com/2.jpg",
+		},
+		{
+			GUID:   "https://example.com/3",
+			Title:  "No id",
+			Header: "Test",
+			Link:   "https://example.com/3",
+		},
+	}
+	if len(posts) != len(want) {
+		t.Fatalf("%d posts, want %d: %+v", len(posts), len(want), posts)
+	}
+	for i := range want {
+		got := posts[i]
+		if !got.Published.Equal(want[i].Published) || !got.Updated.Equal(want[i].Updated) {
+			t.Errorf("item %d: dates %v / %v, want %v / %v", i, got.Published, got.Updated, want[i].Published, want[i].Updated)
+		}
+		got.Published, got.Updated = want[i].Published, want[i].Updated
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("item %d:\n got %+v\nwant %+v", i, got, want[i])
+		}
+	}
+
+	if posts, capped := streamFixture(t, "feed.json", "", 2); !capped || len(posts) != 2 {
+		t.Errorf("maxItems 2: %d posts, capped %v", len(posts), capped)
+	}
+}
+
+func TestStreamJSONFeedErrors(t *testing.T) {
+	tests := []struct {
+		name, doc string
+	}{
+		{"no version", `{"items": []}`},
+		{"other version", `{"version": "1.0", "items": []}`},
+		{"items not an array", `{"version": "https://jsonfeed.org/version/1", "items": {}}`},
+		{"bad id", `{"version": "https://jsonfeed.org/version/1", "items": [{"id": {}, "url": "https://example.com", "title": "x"}]}`},
+		{"not an object", `["version"]`},
+	}
+	for _, tt := range tests {
+		_, err := streamJSONFeed(strings.NewReader(tt.doc), feeds.FeedConfig{}, 10, func(typesPkg.MainStruct) {})
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	stdhtml "html"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Runes Windows-1252 puts in 0x80-0x9F, mapped back to their byte. Every
+// other rune below U+0100 is its own byte.
+var cp1252Bytes = func() map[rune]byte {
+	m := make(map[rune]byte, 32)
+	for b := 0x80; b <= 0x9f; b++ {
+		if r := charmap.Windows1252.DecodeByte(byte(b)); r != utf8.RuneError && r != rune(b) {
+			m[r] = byte(b)
+		}
+	}
+	return m
+}()
+
+var (
+	suffixMu    sync.Mutex
+	suffixCache = map[string]*regexp.Regexp{}
+)
+
+// NormalizeTitles cleans every post's title with NormalizeTitle and drops
+// posts left without one.
+func NormalizeTitles(posts []typesPkg.MainStruct, feed feeds.FeedConfig) ([]typesPkg.MainStruct, error) {
+	suffixes, err := compileSuffixes(feed.TitleSuffixes)
+	if err != nil {
+		return nil, err
+	}
+	markup := !plainTitles(feed)
+
+	kept := posts[:0]
+	for _, post := range posts {
+		post.Title = NormalizeTitle(post.Title, markup, suffixes)
+		if post.Title != "" {
+			kept = append(kept, post)
+		}
+	}
+	return kept, nil
+}
+
+// plainTitles reports whether the feed's titles are plain text already:
+// the HN sources and reddit's listing JSON (read with raw_json=1).
+func plainTitles(feed feeds.FeedConfig) bool {
+	return feed.Source == sourceHN || (feed.Source == sourceReddit && feed.Format != "atom")
+}
+
+// NormalizeTitle turns a feed title into plain text: with markup, tags
+// stripped and entities decoded exactly once; then mojibake repaired,
+// whitespace collapsed and the given trailing patterns (" | Reuters")
+// removed.
+func NormalizeTitle(raw string, markup bool, suffixes []*regexp.Regexp) string {
+	title := raw
+	if markup {
+		// CDATA that survived the XML decoder, e.g. from double-wrapped titles
+		title = strings.ReplaceAll(title, "<![CDATA[", "")
+		title = strings.ReplaceAll(title, "]]>", "")
+		title = plainText(title)
+	}
+	title = fixMojibake(title)
+	title = strings.Join(strings.Fields(title), " ")
+
+	for _, re := range suffixes {
+		if trimmed := strings.TrimSpace(re.ReplaceAllString(title, "")); trimmed != "" {
+			title = trimmed
+		}
+	}
+	return title
+}
+
+// compileSuffixes anchors each pattern at the end of the title. Compiled
+// patterns are shared between runs.
+func compileSuffixes(patterns []string) ([]*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	suffixMu.Lock()
+	defer suffixMu.Unlock()
+
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, ok := suffixCache[p]
+		if !ok {
+			var err error
+			re, err = regexp.Compile(`\s*(?:` + p + `)\s*$`)
+			if err != nil {
+				return nil, fmt.Errorf("invalid title suffix pattern %q: %w", p, err)
+			}
+			suffixCache[p] = re
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// plainText drops the tags of an HTML fragment and decodes the entities in
+// its text exactly once, so escaped markup ("&lt;video&gt;") stays as text.
+// Only known HTML elements count as tags: "Use <T> generics" and "x<y"
+// survive.
+func plainText(fragment string) string {
+	if !strings.ContainsAny(fragment, "<&") {
+		return fragment
+	}
+
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+
+	var b strings.Builder
+	skipping := atom.Atom(0) // inside <script> or <style>
+	for {
+		tt := tokenizer.Next()
+		switch tt {
+		case html.ErrorToken:
+			return b.String()
+
+		case html.TextToken:
+			if skipping == 0 {
+				b.Write(tokenizer.Text())
+			}
+
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			raw := string(tokenizer.Raw()) // TagName lowercases it in place
+			name, _ := tokenizer.TagName()
+			a := atom.Lookup(name)
+			if a == 0 {
+				b.WriteString(stdhtml.UnescapeString(raw))
+				continue
+			}
+			if (a == atom.Script || a == atom.Style) && tt == html.StartTagToken {
+				skipping = a
+			} else if a == skipping && tt == html.EndTagToken {
+				skipping = 0
+			}
+			b.WriteByte(' ')
+		}
+	}
+}
+
+// fixMojibake repairs UTF-8 that was decoded as Windows-1252 somewhere
+// upstream ("Itâ€™s" -> "It’s"). Text is only changed when re-encoding it
+// yields valid UTF-8, so genuine accented text is left alone.
+func fixMojibake(s string) string {
+	if !strings.ContainsAny(s, "ÃÂâ") {
+		return s
+	}
+
+	buf := make([]byte, 0, len(s))
+	for _, r := range s {
+		if b, ok := cp1252Bytes[r]; ok {
+			buf = append(buf, b)
+		} else if r < 0x100 {
+			buf = append(buf, byte(r))
+		} else {
+			return s
+		}
+	}
+
+	if !utf8.Valid(buf) || bytes.Equal(buf, []byte(s)) {
+		return s
+	}
+	return string(buf)
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"testing"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain title", "plain title"},
+		{"<b>Bold</b> text", " Bold  text"},
+		{"Use <T> generics", "Use <T> generics"},
+		{"Map<String, List<T>> explained", "Map<String, List<T>> explained"},
+		{"x<y and y>z", "x<y and y>z"},
+		{"AT&amp;T &amp; Verizon", "AT&T & Verizon"},
+		{"AT&T", "AT&T"},
+		{"<p>One<br/>Two</p>", " One Two "},
+		{"<!-- note -->Title", "Title"},
+		{"Hi<script>alert(1)</script> there", "Hi   there"},
+		{`<a href="https://x">Link</a>`, " Link "},
+
+		// Escaped tag names are text, decoded once
+		{"Why &lt;video&gt; tags matter", "Why <video> tags matter"},
+		{"x &lt;script&gt;alert(1)&lt;/script&gt;", "x <script>alert(1)</script>"},
+		{"<b>Bold</b> &lt;i&gt;x&lt;/i&gt;", " Bold  <i>x</i>"},
+		{"&amp;lt;b&amp;gt; once", "&lt;b&gt; once"},
+	}
+	for _, tt := range tests {
+		if got := plainText(tt.in); got != tt.want {
+			t.Errorf("plainText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	suffixes, err := compileSuffixes([]string{`[|-] Reuters`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in     string
+		markup bool
+		want   string
+	}{
+		{"<b>Bold</b> &lt;i&gt;x&lt;/i&gt;", true, "Bold <i>x</i>"},
+		{"Why &lt;video&gt; tags matter", true, "Why <video> tags matter"},
+		{"Use <T> generics", true, "Use <T> generics"},
+		{"<![CDATA[Markets rally]]>", true, "Markets rally"},
+		{"Itâ€™s   raining - Reuters", true, "It’s raining"},
+
+		// Plain-text sources keep everything but whitespace and mojibake
+		{"The <dialog> element", false, "The <dialog> element"},
+		{"AT&amp;T is not an entity here", false, "AT&amp;T is not an entity here"},
+		{"Itâ€™s  raining", false, "It’s raining"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.in, tt.markup, suffixes); got != tt.want {
+			t.Errorf("NormalizeTitle(%q, %v) = %q, want %q", tt.in, tt.markup, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTitlesPlainSources(t *testing.T) {
+	tests := []struct {
+		feed feeds.FeedConfig
+		want string
+	}{
+		{feeds.FeedConfig{Source: "hn"}, "The <dialog> element"},
+		{feeds.FeedConfig{Source: "reddit"}, "The <dialog> element"},
+		{feeds.FeedConfig{Source: "reddit", Format: "atom"}, "The element"},
+		{feeds.FeedConfig{}, "The element"},
+	}
+	for _, tt := range tests {
+		posts, err := NormalizeTitles([]typesPkg.MainStruct{{Title: "The <dialog> element"}}, tt.feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if posts[0].Title != tt.want {
+			t.Errorf("source %q format %q: %q, want %q", tt.feed.Source, tt.feed.Format, posts[0].Title, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"numerosnumerosnumeros_agg/feeds"
+	"numerosnumerosnumeros_agg/typesPkg"
+)
+
+// Transformer post-processes one item of a feed. Returning false drops the
+// item.
+type Transformer func(post typesPkg.MainStruct) (typesPkg.MainStruct, bool)
+
+// Transformers by the names FeedConfig.Transforms refers to
+var transformers = map[string]Transformer{
+	"reddit":              redditTransform,
+	"techmeme-cluster":    techmemeTransform,
+	"slashdot-dept-strip": slashdotTransform,
+	"tldr-sponsor-drop":   tldrSponsorTransform,
+}
+
+var (
+	// "Title (Author / Publication)" or "Title (Publication)"
+	techmemeAttributionRe = regexp.MustCompile(`\s*\(([^()]+)\)\s*$`)
+	hrefRe                = regexp.MustCompile(`(?i)<a\s[^>]*href\s*=\s*["']([^"']+)["']`)
+
+	slashdotDeptRe = regexp.MustCompile(`(?i)\s*from the [^<]*? dept\.?`)
+	slashdotMoreRe = regexp.MustCompile(`(?is)<p>\s*<div class="share_submission".*$|read more of this story at slashdot\.?`)
+
+	tldrSponsorRe = regexp.MustCompile(`(?i)\(\s*sponsor(ed)?\s*\)`)
+)
+
+// PreparePosts runs the per-item stages that follow parsing: the feed's
+// transformers, title normalisation and link cleaning. Dedup keys are left
+// to ApplyGUIDStrategy.
+func PreparePosts(posts []typesPkg.MainStruct, feed feeds.FeedConfig) ([]typesPkg.MainStruct, error) {
+	posts, err := ApplyTransforms(posts, feed)
+	if err != nil {
+		return nil, err
+	}
+	posts, err = NormalizeTitles(posts, feed)
+	if err != nil {
+		return nil, err
+	}
+	return CleanLinks(posts), nil
+}
+
+// ApplyTransforms runs the feed's transformers, in order, over every post.
+// Reddit feeds always get the "reddit" transformer first.
+func ApplyTransforms(posts []typesPkg.MainStruct, feed feeds.FeedConfig) ([]typesPkg.MainStruct, error) {
+	names := feed.Transforms
+	if feed.Source == sourceReddit {
+		names = append([]string{"reddit"}, names...)
+	}
+	if len(names) == 0 {
+		return posts, nil
+	}
+
+	chain := make([]Transformer, 0, len(names))
+	for _, name := range names {
+		t, ok := transformers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown transform %q", name)
+		}
+		chain = append(chain, t)
+	}
+
+	kept := posts[:0]
+	for _, post := range posts {
+		ok := true
+		for _, t := range chain {
+			if post, ok = t(post); !ok {
+				break
+			}
+		}
+		if ok {
+			kept = append(kept, post)
+		}
+	}
+	return kept, nil
+}
+
+// redditTransform handles reddit's Atom feed, where the entry links to the
+// comments and the outbound URL is the "[link]" anchor in the content.
+// Listing JSON posts have no such anchor and pass through unchanged.
+func redditTransform(post typesPkg.MainStruct) (typesPkg.MainStruct, bool) {
+	return withRedditAtomLinks(post, post.Description), true
+}
+
+// techmemeTransform points the post at the story Techmeme headlines instead
+// of its own river page, and moves the "(Author / Publication)" attribution
+// out of the title.
+func techmemeTransform(post typesPkg.MainStruct) (typesPkg.MainStruct, bool) {
+	if m := techmemeAttributionRe.FindStringSubmatch(post.Title); m != nil {
+		post.Title = strings.TrimSpace(post.Title[:len(post.Title)-len(m[0])])
+		if post.Author == "" {
+			post.Author = strings.TrimSpace(m[1])
+		}
+	}
+
+	for _, m := range hrefRe.FindAllStringSubmatch(post.Description, -1) {
+		u, err := url.Parse(strings.TrimSpace(m[1]))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		if host := strings.ToLower(u.Hostname()); host == "techmeme.com" || strings.HasSuffix(host, ".techmeme.com") {
+			continue
+		}
+		post.Link = u.String()
+		break
+	}
+
+	return post, true
+}
+
+// slashdotTransform drops the "from the ... dept." line and the share /
+// "Read more" boilerplate from Slashdot descriptions.
+func slashdotTransform(post typesPkg.MainStruct) (typesPkg.MainStruct, bool) {
+	desc := slashdotDeptRe.ReplaceAllString(post.Description, "")
+	desc = slashdotMoreRe.ReplaceAllString(desc, "")
+	post.Description = strings.TrimSpace(desc)
+	return post, true
+}
+
+// tldrSponsorTransform drops the sponsored entries TLDR mixes into its feeds.
+func tldrSponsorTransform(post typesPkg.MainStruct) (typesPkg.MainStruct, bool) {
+	if tldrSponsorRe.MatchString(post.Title) {
+		return post, false
+	}
+	for _, c := range post.Categories {
+		if strings.EqualFold(c, "sponsor") || strings.EqualFold(c, "sponsored") {
+			return post, false
+		}
+	}
+	return post, true
+}
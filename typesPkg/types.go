@@ -1,10 +1,49 @@
 package typesPkg
 
+import "time"
+
 type MainStruct struct {
-	GUID   string
-	Title  string
-	Link   string
-	Header string
+	GUID           string
+	Title          string
+	Link           string
+	Header         string
+	Published      time.Time // UTC, zero when the feed doesn't provide a date
+	Updated        time.Time // UTC, zero when the feed doesn't provide a date
+	Description    string    // summary as found in the feed, may contain HTML
+	Author         string
+	Categories     []string
+	Enclosures     []Enclosure
+	Image          string     // thumbnail / lead image URL
+	AltGUIDs       []string   // further dedup keys, checked and marked published alongside GUID
+	Related        []Coverage // other outlets carrying the same story
+	DiscussionLink string     // comments page, when the source has one apart from Link
+	Score          int        // votes / points, for sources that have them
+	Comments       int
+}
+
+type Coverage struct {
+	Header string `json:"header"`
+	Link   string `json:"link"`
+}
+
+// Story is a posted article as remembered for near-duplicate suppression
+type Story struct {
+	Key            int64 // sort key of the stored record, 0 until saved
+	GUID           string
+	Title          string
+	Header         string
+	Link           string
+	Words          []string // tools.StoryWords
+	Posted         time.Time
+	MessageID      int64 // Telegram message the story was posted as, 0 if unknown
+	Related        []Coverage
+	DiscussionLink string
+}
+
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64
 }
 
 type Agents struct {
@@ -12,3 +51,38 @@ type Agents struct {
 	Chrome string
 	Reader string
 }
+
+// HTTP cache validators remembered per feed for conditional GETs
+type FeedValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// Per-feed health, used to back off from feeds that keep failing
+type FeedHealth struct {
+	LastSuccess         time.Time
+	LastAttempt         time.Time
+	ConsecutiveFailures int
+	LastError           string
+	LastItemCount       int
+	OpenUntil           time.Time // circuit breaker: skip the feed until then
+}
+
+// Everything persisted per feed between runs
+type FeedState struct {
+	Validators FeedValidators
+	Health     FeedHealth
+	Cookies    []StoredCookie
+	HighWater  time.Time // newest publish date sent so far
+}
+
+// Cookie persisted between runs for feeds with FeedConfig.CookieJar
+type StoredCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires"`
+	Secure   bool      `json:"secure,omitempty"`
+	HostOnly bool      `json:"host_only,omitempty"`
+}